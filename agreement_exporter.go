@@ -1,6 +1,7 @@
 package main
 
 import (
+	"log"
 	"time"
 )
 
@@ -8,7 +9,8 @@ func makeAnnotation(agreement Agreement, newAnnotationName string) Annotation {
 	toreRelationships := agreement.TORERelationships
 	codeAlternatives := agreement.CodeAlternatives
 
-	acceptedCodes, acceptedToreRelationships := makeAcceptedToreRelationshipsAndCodes(toreRelationships, codeAlternatives)
+	codeAlternatives = addAcceptedRelationshipsToAcceptedCodes(toreRelationships, codeAlternatives, agreement.RelationshipAlternatives)
+	acceptedCodes, acceptedToreRelationships := makeAcceptedToreRelationshipsAndCodes(toreRelationships, codeAlternatives, agreement.RelationshipAlternatives)
 	updatedTokens := updateTokens(agreement, acceptedCodes)
 
 	var newAnnotation = Annotation{
@@ -61,18 +63,31 @@ func updateTokens(agreement Agreement, acceptedCodes []Code) []Token {
 
 // Only accepted codes are used in the annotation, so all codes and relationships that are not accepted have to be removed
 // The index of codes and relationships has to be adapted as well
+// Declined relationships are removed, pending relationships are kept, if their code is accepted. Relationships are only
+// declined by the automatic merge or an adjudicator, so agreements that were not merged keep all their relationships
 func makeAcceptedToreRelationshipsAndCodes(
 	toreRelationships []TORERelationship,
 	codeAlternatives []CodeAlternatives,
+	relationshipAlternatives []RelationshipAlternatives,
 ) ([]Code, []TORERelationship) {
 	codeIndex := 0
 	var acceptedCodes []Code
 	var acceptedToreRelationships []TORERelationship
 
+	declinedRelationships := map[int]bool{}
+	for _, relationshipAlternative := range relationshipAlternatives {
+		if relationshipAlternative.MergeStatus == "Declined" {
+			declinedRelationships[relationshipAlternative.Index] = true
+		}
+	}
+
 	for i, codeAlternative := range codeAlternatives {
 		if codeAlternative.MergeStatus == "Accepted" {
 			*codeAlternatives[i].Code.Index = codeIndex
 			for _, usedRelIndex := range codeAlternative.Code.RelationshipMemberships {
+				if declinedRelationships[*usedRelIndex] {
+					continue
+				}
 				for j, toreRel := range toreRelationships {
 					if toreRel.TOREEntity != nil {
 						if *usedRelIndex == *toreRel.Index {
@@ -103,3 +118,66 @@ func makeAcceptedToreRelationshipsAndCodes(
 	}
 	return acceptedCodes, acceptedToreRelationships
 }
+
+// Accepted relationships are added to the accepted code of the span group of their source code. The span group of an
+// accepted code is its span and the other spans of its merge details
+// Relationships without an accepted code for their source span cannot be exported and are reported
+func addAcceptedRelationshipsToAcceptedCodes(
+	toreRelationships []TORERelationship,
+	codeAlternatives []CodeAlternatives,
+	relationshipAlternatives []RelationshipAlternatives,
+) []CodeAlternatives {
+	relationshipMap := createRelationshipMapOfList(toreRelationships)
+	positionsOfCodes := map[int]int{}
+	acceptedCodesOfSpans := map[string]int{}
+	for i, codeAlternative := range codeAlternatives {
+		positionsOfCodes[codeAlternative.Index] = i
+		if codeAlternative.MergeStatus != "Accepted" {
+			continue
+		}
+		spanKeys := []string{getSpanKey(codeAlternative.Code.Tokens)}
+		if codeAlternative.MergeDetails != nil {
+			for _, otherSpan := range codeAlternative.MergeDetails.OtherSpans {
+				spanKeys = append(spanKeys, getSpanKeyOfIndices(otherSpan))
+			}
+		}
+		for _, spanKey := range spanKeys {
+			if _, ok := acceptedCodesOfSpans[spanKey]; !ok {
+				acceptedCodesOfSpans[spanKey] = i
+			}
+		}
+	}
+
+	for _, relationshipAlternative := range relationshipAlternatives {
+		if relationshipAlternative.MergeStatus != "Accepted" {
+			continue
+		}
+		toreRel, ok := relationshipMap[relationshipAlternative.Index]
+		if !ok || toreRel.TOREEntity == nil {
+			continue
+		}
+		// The source code itself is preferred, if it is accepted
+		i, ok := positionsOfCodes[*toreRel.TOREEntity]
+		if !ok {
+			continue
+		}
+		if codeAlternatives[i].MergeStatus != "Accepted" {
+			i, ok = acceptedCodesOfSpans[getSpanKey(codeAlternatives[i].Code.Tokens)]
+			if !ok {
+				log.Printf("Accepted relationship %d has no accepted code for its source span and is not exported\n", relationshipAlternative.Index)
+				continue
+			}
+		}
+		isMember := false
+		for _, usedRelIndex := range codeAlternatives[i].Code.RelationshipMemberships {
+			if *usedRelIndex == relationshipAlternative.Index {
+				isMember = true
+			}
+		}
+		if !isMember {
+			relIndex := relationshipAlternative.Index
+			codeAlternatives[i].Code.RelationshipMemberships = append(codeAlternatives[i].Code.RelationshipMemberships, &relIndex)
+		}
+	}
+	return codeAlternatives
+}
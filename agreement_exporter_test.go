@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+)
+
+// The code of A is accepted for the span group of [0 1] and [0 1 2]. The accepted relationship of the declined code of B
+// is moved to it, the pending relationship 0 of A follows it and the declined relationship 3 of A is not exported
+// The pending relationship of the declined code of C is not exported either
+func TestMakeAnnotationDropsOnlyDeclinedRelationships(t *testing.T) {
+	var codeAlternatives = []CodeAlternatives{
+		makeTestCodeAlternative(0, "A", "Accepted", "Task", "x", 0, 1),
+		makeTestCodeAlternative(1, "B", "Declined", "Task", "x", 0, 1, 2),
+		makeTestCodeAlternative(2, "C", "Declined", "Task", "x", 0, 1),
+	}
+	codeAlternatives[0].MergeDetails = &MergeDetails{
+		SpanMatchingStrategy: spanMatchingIoU,
+		ChosenSpan:           []int{0, 1},
+		OtherSpans:           [][]int{{0, 1, 2}},
+	}
+	var toreRelationships = []TORERelationship{
+		makeTestRelationship(0, 0, "relatesTo", 5),
+		makeTestRelationship(1, 1, "refersTo", 4),
+		makeTestRelationship(2, 2, "refersTo", 3),
+		makeTestRelationship(3, 0, "refersTo", 2),
+	}
+	for i := range codeAlternatives {
+		codeAlternatives[i].Code.RelationshipMemberships = []*int{getIntPointer(i)}
+	}
+	codeAlternatives[0].Code.RelationshipMemberships = append(codeAlternatives[0].Code.RelationshipMemberships, getIntPointer(3))
+	var relationshipAlternatives = initializeRelationshipAlternatives(toreRelationships, codeAlternatives)
+	var statusesOfRelationships = map[int]string{1: "Accepted", 3: "Declined"}
+	for i, relationshipAlternative := range relationshipAlternatives {
+		if status, ok := statusesOfRelationships[relationshipAlternative.Index]; ok {
+			relationshipAlternatives[i].MergeStatus = status
+		}
+	}
+
+	var annotation = makeAnnotation(Agreement{
+		Tokens:                   makeTestTokens(6),
+		CodeAlternatives:         codeAlternatives,
+		TORERelationships:        toreRelationships,
+		RelationshipAlternatives: relationshipAlternatives,
+	}, "merged")
+
+	if len(annotation.Codes) != 1 {
+		t.Fatalf("number of codes = %d, want 1", len(annotation.Codes))
+	}
+	if len(annotation.TORERelationships) != 2 {
+		t.Fatalf("number of relationships = %d, want 2", len(annotation.TORERelationships))
+	}
+	var wantRelationships = []struct {
+		name        string
+		targetToken int
+	}{
+		{"relatesTo", 5},
+		{"refersTo", 4},
+	}
+	for i, relationship := range annotation.TORERelationships {
+		var want = wantRelationships[i]
+		if relationship.RelationshipName != want.name || *relationship.TargetTokens[0] != want.targetToken || *relationship.TOREEntity != 0 {
+			t.Errorf("relationship %d = %s to %d of code %d, want %s to %d of code 0", i, relationship.RelationshipName, *relationship.TargetTokens[0], *relationship.TOREEntity, want.name, want.targetToken)
+		}
+	}
+	var memberships = annotation.Codes[0].RelationshipMemberships
+	if len(memberships) != 2 || *memberships[0] != 0 || *memberships[1] != 1 {
+		t.Errorf("relationships of the code = %v, want the relationships 0 and 1", memberships)
+	}
+}
+
+// The other spans of merge details are unsorted token indices, their keys have to match the keys of the codes
+func TestGetSpanKeyOfIndicesMatchesGetSpanKey(t *testing.T) {
+	var code = makeTestCodeAlternative(0, "A", "Pending", "Task", "x", 2, 0, 1).Code
+	if getSpanKeyOfIndices([]int{1, 2, 0}) != getSpanKey(code.Tokens) {
+		t.Errorf("key of indices = %s, want %s", getSpanKeyOfIndices([]int{1, 2, 0}), getSpanKey(code.Tokens))
+	}
+}
@@ -11,7 +11,10 @@ type RelevantAgreementFields struct {
 	Tokens            []Token            `json:"tokens" bson:"tokens"`
 	TORERelationships []TORERelationship `json:"tore_relationships" bson:"tore_relationships"`
 
-	CodeAlternatives []CodeAlternatives `json:"code_alternatives" bson:"code_alternatives"`
+	CodeAlternatives         []CodeAlternatives         `json:"code_alternatives" bson:"code_alternatives"`
+	RelationshipAlternatives []RelationshipAlternatives `json:"relationship_alternatives" bson:"relationship_alternatives"`
+
+	LabelProposals []LabelProposal `json:"label_proposals,omitempty" bson:"label_proposals,omitempty"`
 }
 
 func initializeInfoFromAnnotations(
@@ -22,6 +25,37 @@ func initializeInfoFromAnnotations(
 	[]TORERelationship,
 	[]CodeAlternatives,
 	error,
+) {
+	annotations, err := getAnnotationsByName(w, annotationNames)
+	if err != nil {
+		return *new([]DocWrapper), *new([]Token), *new([]TORERelationship), *new([]CodeAlternatives), err
+	}
+	docs, tokens, toreRelationships, codes := initializeInfoFromFetchedAnnotations(annotationNames, annotations)
+	return docs, tokens, toreRelationships, codes, nil
+}
+
+// Returns the annotations in the order of their names
+func getAnnotationsByName(w http.ResponseWriter, annotationNames []string) ([]Annotation, error) {
+	var annotations []Annotation
+	for _, annotationName := range annotationNames {
+		annotation, err := RESTGetAnnotation(annotationName)
+		handleErrorWithResponse(w, err, "ERROR retrieving annotation")
+		if err != nil {
+			return nil, err
+		}
+		annotations = append(annotations, annotation)
+	}
+	return annotations, nil
+}
+
+// Fills the alternatives of the annotations, which have to be in the order of their names
+func initializeInfoFromFetchedAnnotations(
+	annotationNames []string, annotations []Annotation,
+) (
+	[]DocWrapper,
+	[]Token,
+	[]TORERelationship,
+	[]CodeAlternatives,
 ) {
 	var codes []CodeAlternatives
 	var tokens []Token
@@ -32,11 +66,7 @@ func initializeInfoFromAnnotations(
 	var relationshipIndexCounter = 0
 
 	for i, annotationName := range annotationNames {
-		annotation, err := RESTGetAnnotation(annotationName)
-		handleErrorWithResponse(w, err, "ERROR retrieving annotation")
-		if err != nil {
-			return *new([]DocWrapper), *new([]Token), *new([]TORERelationship), *new([]CodeAlternatives), err
-		}
+		var annotation = annotations[i]
 
 		log.Printf("Getting info from: " + annotationName)
 
@@ -83,5 +113,32 @@ func initializeInfoFromAnnotations(
 
 	}
 
-	return docs, tokens, toreRelationships, codes, nil
+	return docs, tokens, toreRelationships, codes
+}
+
+// Every relationship is an alternative of the annotation of its source code, all relationships are set to pending
+func initializeRelationshipAlternatives(
+	toreRelationships []TORERelationship,
+	codeAlternatives []CodeAlternatives,
+) []RelationshipAlternatives {
+	var annotationNamesOfCodes = map[int]string{}
+	for _, codeAlternative := range codeAlternatives {
+		annotationNamesOfCodes[codeAlternative.Index] = codeAlternative.AnnotationName
+	}
+	var relationshipAlternatives = []RelationshipAlternatives{}
+	for _, toreRelationship := range toreRelationships {
+		if toreRelationship.TOREEntity == nil || toreRelationship.Index == nil {
+			continue
+		}
+		annotationName, ok := annotationNamesOfCodes[*toreRelationship.TOREEntity]
+		if !ok {
+			continue
+		}
+		relationshipAlternatives = append(relationshipAlternatives, RelationshipAlternatives{
+			AnnotationName: annotationName,
+			MergeStatus:    "Pending",
+			Index:          *toreRelationship.Index,
+		})
+	}
+	return relationshipAlternatives
 }
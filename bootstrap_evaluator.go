@@ -0,0 +1,130 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+const (
+	bootstrapUnitToken    = "token"
+	bootstrapUnitDocument = "document"
+
+	defaultBootstrapConfidenceLevel = 0.95
+	// Every iteration calculates all kappas again, so requests with more iterations are rejected
+	maxBootstrapIterations = 10000
+)
+
+// ConfidenceInterval model
+type ConfidenceInterval struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+}
+
+// BootstrapConfidenceIntervals model, the percentile confidence intervals of all kappas by their name
+type BootstrapConfidenceIntervals struct {
+	Iterations      int                           `json:"iterations"`
+	Seed            int64                         `json:"seed"`
+	Unit            string                        `json:"unit"`
+	ConfidenceLevel float64                       `json:"confidence_level"`
+	Intervals       map[string]ConfidenceInterval `json:"intervals"`
+}
+
+// Resamples the coded tokens, or the documents with all their coded tokens, with replacement and
+// calculates the kappas for every sample
+func getBootstrapConfidenceIntervals(
+	agreement Agreement,
+	toreCategories ToreCategories,
+	toreRelationships ToreRelationships,
+	iterations int,
+	seed int64,
+	unit string,
+	confidenceLevel float64,
+) BootstrapConfidenceIntervals {
+	if unit != bootstrapUnitDocument {
+		unit = bootstrapUnitToken
+	}
+	if confidenceLevel <= 0 || confidenceLevel >= 1 {
+		confidenceLevel = defaultBootstrapConfidenceLevel
+	}
+
+	dataMatrix, dataMatrixForRowCalculation, reliabilityDataMatrix := getDataMatrices(agreement, toreCategories, toreRelationships)
+
+	// Every resampling unit is a group of rows of the data matrices
+	var rowGroups [][]int
+	if unit == bootstrapUnitDocument {
+		rowGroups = groupRowsByDocument(agreement)
+	} else {
+		for row := range dataMatrix {
+			rowGroups = append(rowGroups, []int{row})
+		}
+	}
+
+	var random = rand.New(rand.NewSource(seed))
+	var samplesOfKappas = map[string][]float64{}
+	for iteration := 0; iteration < iterations && len(rowGroups) != 0; iteration++ {
+		var sampledDataMatrix []map[int]int
+		var sampledDataMatrixForRowCalculation []map[int]int
+		var sampledReliabilityDataMatrix []map[int]int
+		for range rowGroups {
+			for _, row := range rowGroups[random.Intn(len(rowGroups))] {
+				sampledDataMatrix = append(sampledDataMatrix, dataMatrix[row])
+				sampledDataMatrixForRowCalculation = append(sampledDataMatrixForRowCalculation, dataMatrixForRowCalculation[row])
+				sampledReliabilityDataMatrix = append(sampledReliabilityDataMatrix, reliabilityDataMatrix[row])
+			}
+		}
+		kappas := calculateKappasFromDataMatrices(sampledDataMatrix, sampledDataMatrixForRowCalculation, sampledReliabilityDataMatrix)
+		for kappaName, kappa := range kappas {
+			samplesOfKappas[kappaName] = append(samplesOfKappas[kappaName], kappa)
+		}
+	}
+
+	var intervals = map[string]ConfidenceInterval{}
+	for kappaName, samples := range samplesOfKappas {
+		sort.Float64s(samples)
+		intervals[kappaName] = ConfidenceInterval{
+			Lower: getPercentile(samples, (1.0-confidenceLevel)/2),
+			Upper: getPercentile(samples, 1.0-(1.0-confidenceLevel)/2),
+		}
+	}
+	return BootstrapConfidenceIntervals{
+		Iterations:      iterations,
+		Seed:            seed,
+		Unit:            unit,
+		ConfidenceLevel: confidenceLevel,
+		Intervals:       intervals,
+	}
+}
+
+// Returns the rows of the data matrices of every document, documents without coded tokens are left out
+func groupRowsByDocument(agreement Agreement) [][]int {
+	var rowOfToken = map[int]int{}
+	for row, tokenIndex := range getCodedTokenIndices(agreement) {
+		rowOfToken[tokenIndex] = row
+	}
+
+	var rowGroups [][]int
+	for _, doc := range agreement.Docs {
+		if doc.BeginIndex == nil || doc.EndIndex == nil {
+			continue
+		}
+		var rows []int
+		for tokenIndex := *doc.BeginIndex; tokenIndex < *doc.EndIndex; tokenIndex++ {
+			if row, ok := rowOfToken[tokenIndex]; ok {
+				rows = append(rows, row)
+			}
+		}
+		if len(rows) != 0 {
+			rowGroups = append(rowGroups, rows)
+		}
+	}
+	return rowGroups
+}
+
+// Returns the percentile of sorted samples, interpolating linearly between the closest ranks
+func getPercentile(sortedSamples []float64, percentile float64) float64 {
+	var position = percentile * float64(len(sortedSamples)-1)
+	var lower = int(math.Floor(position))
+	var upper = int(math.Ceil(position))
+	return sortedSamples[lower] + (position-float64(lower))*(sortedSamples[upper]-sortedSamples[lower])
+}
@@ -0,0 +1,77 @@
+package main
+
+// CategoryAgreement model, one-vs-rest agreement of a single tore category
+type CategoryAgreement struct {
+	Category          string  `json:"category"`
+	Kappa             float64 `json:"kappa"`
+	ObservedAgreement float64 `json:"observed_agreement"`
+	ExpectedAgreement float64 `json:"expected_agreement"`
+}
+
+func getCategoryAgreements(
+	agreement Agreement,
+	toreCategories ToreCategories,
+) []CategoryAgreement {
+	var tokenMap = groupCodeAlternativesByToken(agreement.CodeAlternatives)
+	var numberOfAnnotations = len(agreement.Annotations)
+
+	var categoryAgreements []CategoryAgreement
+	for _, toreCategory := range toreCategories.Tores {
+		// For every coded token, count the annotations that assigned the category
+		var positiveCounts []int
+		for _, token := range agreement.Tokens {
+			var tokenIndex = *token.Index
+			if len(tokenMap[tokenIndex]) == 0 {
+				continue
+			}
+			var positives = 0
+			for _, codesOfAnnotation := range getCodesOfAnnotationsForToken(tokenMap[tokenIndex], agreement.Annotations) {
+				for _, codeAlternative := range codesOfAnnotation {
+					if codeAlternative.Code.Tore == toreCategory {
+						positives++
+						break
+					}
+				}
+			}
+			positiveCounts = append(positiveCounts, positives)
+		}
+		kappa, observedAgreement, expectedAgreement := calculateBinaryFleissKappa(positiveCounts, numberOfAnnotations)
+		// Special cases, when Kappa is either smaller than 0 or None because some denominator is 0
+		if (kappa < 0) || (kappa != kappa) {
+			kappa = 0.0
+		}
+		categoryAgreements = append(categoryAgreements, CategoryAgreement{
+			Category:          toreCategory,
+			Kappa:             kappa,
+			ObservedAgreement: observedAgreement,
+			ExpectedAgreement: expectedAgreement,
+		})
+	}
+	return categoryAgreements
+}
+
+// Fleiss kappa for a yes/no decision, positiveCounts contains the number of raters saying yes for every token
+// Returns the kappa, the observed and the expected agreement
+func calculateBinaryFleissKappa(positiveCounts []int, numberOfRaters int) (float64, float64, float64) {
+	// Without tokens or a second rater there is nothing to agree on
+	if len(positiveCounts) == 0 || numberOfRaters < 2 {
+		return 0.0, 0.0, 0.0
+	}
+	var sumOfPi = float64(0)
+	var sumOfPositives = float64(0)
+	for _, positives := range positiveCounts {
+		var negatives = numberOfRaters - positives
+		sumOfPi += float64(positives*(positives-1)+negatives*(negatives-1)) / float64(numberOfRaters*(numberOfRaters-1))
+		sumOfPositives += float64(positives)
+	}
+	var numberOfTokens = float64(len(positiveCounts))
+	var observedAgreement = sumOfPi / numberOfTokens
+	var pPositive = sumOfPositives / (numberOfTokens * float64(numberOfRaters))
+	var expectedAgreement = pPositive*pPositive + (1.0-pPositive)*(1.0-pPositive)
+
+	// This is only for the special case, when denominator is 0
+	if (1.0 - expectedAgreement) == 0.0 {
+		return 1.0, observedAgreement, expectedAgreement
+	}
+	return (observedAgreement - expectedAgreement) / (1.0 - expectedAgreement), observedAgreement, expectedAgreement
+}
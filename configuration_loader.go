@@ -0,0 +1,30 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+)
+
+// Paths of JSON files with defaults for the statistics, used if a request contains none
+var toreCategoryDistancesFile = os.Getenv("TORE_CATEGORY_DISTANCES_FILE")
+var toreLevelMappingFile = os.Getenv("TORE_LEVEL_MAPPING_FILE")
+
+// readConfigurationFile parses a JSON file into target, nothing happens if path is empty
+func readConfigurationFile(path string, target interface{}) error {
+	if path == "" {
+		return nil
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Printf("ERR reading configuration %s %v\n", path, err)
+		return err
+	}
+	err = json.Unmarshal(content, target)
+	if err != nil {
+		log.Printf("ERR parsing configuration %s %v\n", path, err)
+		return err
+	}
+	return nil
+}
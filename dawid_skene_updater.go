@@ -0,0 +1,284 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// Aggregation mode of the automatic merge, without it codes are merged by majority
+const aggregationModeDawidSkene = "dawid_skene"
+
+const (
+	defaultDawidSkeneConfidenceThreshold = 0.9
+	dawidSkeneMaxIterations              = 50
+	dawidSkeneTolerance                  = 1e-6
+	// Added to every count, so that labels an annotator never used do not get a probability of 0
+	dawidSkeneSmoothing = 0.01
+	// Label of annotations without a code on a token. It cannot be a tore or name, so an empty tore or name stays a label
+	dawidSkeneUncodedLabel = "\x00uncoded"
+)
+
+// LabelProposal model, the most likely code of a span with its posterior confidence
+// IsApplied is set, if the confidence reached the threshold and the code was accepted
+type LabelProposal struct {
+	TokenIndices         []int   `json:"token_indices"`
+	Tore                 string  `json:"tore"`
+	Name                 string  `json:"name"`
+	Confidence           float64 `json:"confidence"`
+	CodeAlternativeIndex int     `json:"code_alternative_index"`
+	IsApplied            bool    `json:"is_applied"`
+}
+
+// Proposes the most likely code of every span with Dawid-Skene over the tores and names of the tokens
+// The confidence of a code is the product of the mean posteriors of its tore and name over its tokens. Only fields
+// that must match are part of it, without any the confidence is the mean posterior that the tokens are coded at all
+// Proposals with a confidence of at least confidenceThreshold are accepted like the candidate of the majority merge:
+// the codes of the span that agree on the fields that must match are the candidate, the others are declined
+func updateStatusOfCodeAlternativesWithDawidSkene(
+	codeAlternatives []CodeAlternatives,
+	toreRelationships []TORERelationship,
+	annotationNames []string,
+	confidenceThreshold float64,
+	spanMatching SpanMatching,
+	mergePolicy MergePolicy,
+) ([]CodeAlternatives, []LabelProposal) {
+	var relationshipMap = createRelationshipMapOfList(toreRelationships)
+	var tokenIndices, itemsOfTores = getLabelsOfTokens(codeAlternatives, annotationNames, func(code Code) string { return code.Tore })
+	_, itemsOfNames := getLabelsOfTokens(codeAlternatives, annotationNames, func(code Code) string { return code.Name })
+	var rowsOfTokens = map[int]int{}
+	for row, tokenIndex := range tokenIndices {
+		rowsOfTokens[tokenIndex] = row
+	}
+	var posteriorsOfTores = estimateDawidSkenePosteriors(itemsOfTores, annotationNames)
+	var posteriorsOfNames = estimateDawidSkenePosteriors(itemsOfNames, annotationNames)
+
+	var getMeanPosterior = func(posteriors []map[string]float64, tokens []*int, label string) float64 {
+		var sumOfPosteriors = float64(0)
+		for _, token := range tokens {
+			sumOfPosteriors += posteriors[rowsOfTokens[*token]][label]
+		}
+		return sumOfPosteriors / float64(len(tokens))
+	}
+
+	var spanGroupsOfCodeAlternatives = getSpanGroupsOfCodeAlternatives(codeAlternatives, spanMatching)
+	var spanGroups []int
+	var codeAlternativesOfSpanGroups = map[int][]int{}
+	for i, spanGroup := range spanGroupsOfCodeAlternatives {
+		if _, ok := codeAlternativesOfSpanGroups[spanGroup]; !ok {
+			spanGroups = append(spanGroups, spanGroup)
+		}
+		codeAlternativesOfSpanGroups[spanGroup] = append(codeAlternativesOfSpanGroups[spanGroup], i)
+	}
+
+	var labelProposals = []LabelProposal{}
+	for _, spanGroup := range spanGroups {
+		var proposedIndex = -1
+		var confidence = float64(0)
+		for _, i := range codeAlternativesOfSpanGroups[spanGroup] {
+			var code = codeAlternatives[i].Code
+			if len(code.Tokens) == 0 {
+				continue
+			}
+			var codeConfidence = 1.0
+			if mergePolicy.Tore == mergePolicyMustMatch {
+				codeConfidence *= getMeanPosterior(posteriorsOfTores, code.Tokens, code.Tore)
+			}
+			if mergePolicy.Name == mergePolicyMustMatch {
+				codeConfidence *= getMeanPosterior(posteriorsOfNames, code.Tokens, code.Name)
+			}
+			if mergePolicy.Tore != mergePolicyMustMatch && mergePolicy.Name != mergePolicyMustMatch {
+				codeConfidence = 1 - getMeanPosterior(posteriorsOfTores, code.Tokens, dawidSkeneUncodedLabel)
+			}
+			if proposedIndex == -1 || codeConfidence > confidence {
+				proposedIndex, confidence = i, codeConfidence
+			}
+		}
+		if proposedIndex == -1 {
+			continue
+		}
+
+		var isApplied = confidence >= confidenceThreshold
+		if isApplied {
+			var proposedKey = getCodeMergeCandidateKey(codeAlternatives[proposedIndex].Code, spanGroup, relationshipMap, mergePolicy)
+			var candidate = CodeMergeCandidate{spanGroup: spanGroup}
+			for _, i := range codeAlternativesOfSpanGroups[spanGroup] {
+				if getCodeMergeCandidateKey(codeAlternatives[i].Code, spanGroup, relationshipMap, mergePolicy) == proposedKey {
+					candidate.codeAlternativeIndices = append(candidate.codeAlternativeIndices, i)
+				}
+			}
+			acceptCodeMergeCandidate(codeAlternatives, proposedIndex, codeAlternativesOfSpanGroups[spanGroup], candidate, relationshipMap, spanMatching, mergePolicy)
+		}
+		// The proposal shows the merged fields of an accepted code
+		labelProposals = append(labelProposals, LabelProposal{
+			TokenIndices:         getSortedTokenIndices(codeAlternatives[proposedIndex].Code.Tokens),
+			Tore:                 codeAlternatives[proposedIndex].Code.Tore,
+			Name:                 codeAlternatives[proposedIndex].Code.Name,
+			Confidence:           confidence,
+			CodeAlternativeIndex: codeAlternatives[proposedIndex].Index,
+			IsApplied:            isApplied,
+		})
+	}
+	return codeAlternatives, labelProposals
+}
+
+// Returns the coded tokens in ascending order, and for every token the labels every annotation gave it
+// Annotations without a code on a token give it the label dawidSkeneUncodedLabel
+func getLabelsOfTokens(
+	codeAlternatives []CodeAlternatives,
+	annotationNames []string,
+	getLabel func(code Code) string,
+) ([]int, []map[string][]string) {
+	var labelsOfTokens = map[int]map[string][]string{}
+	for _, codeAlternative := range codeAlternatives {
+		for _, token := range codeAlternative.Code.Tokens {
+			if _, ok := labelsOfTokens[*token]; !ok {
+				labelsOfTokens[*token] = map[string][]string{}
+			}
+			labelsOfTokens[*token][codeAlternative.AnnotationName] = append(labelsOfTokens[*token][codeAlternative.AnnotationName], getLabel(codeAlternative.Code))
+		}
+	}
+	var tokenIndices []int
+	for tokenIndex := range labelsOfTokens {
+		tokenIndices = append(tokenIndices, tokenIndex)
+	}
+	sort.Ints(tokenIndices)
+
+	var items []map[string][]string
+	for _, tokenIndex := range tokenIndices {
+		var item = map[string][]string{}
+		for _, annotationName := range annotationNames {
+			item[annotationName] = labelsOfTokens[tokenIndex][annotationName]
+			if len(item[annotationName]) == 0 {
+				item[annotationName] = []string{dawidSkeneUncodedLabel}
+			}
+		}
+		items = append(items, item)
+	}
+	return tokenIndices, items
+}
+
+// Dawid-Skene EM, returns for every item the posterior probability of its true labels
+// The confusion matrices of the annotations are sparse, and only labels given to an item are possible true labels
+// of it, so that free text labels like names stay feasible. Labels and annotations are numbered for the iterations
+func estimateDawidSkenePosteriors(items []map[string][]string, annotationNames []string) []map[string]float64 {
+	var labelIds = map[string]int{}
+	var labels []string
+	var getLabelId = func(label string) int {
+		if id, ok := labelIds[label]; ok {
+			return id
+		}
+		labelIds[label] = len(labels)
+		labels = append(labels, label)
+		return len(labels) - 1
+	}
+
+	// The posteriors start with the share of votes for every label
+	var labelsOfItems = make([][]int, len(items))
+	var labelsOfAnnotations = make([][][]int, len(items))
+	var posteriors = make([][]float64, len(items))
+	for i, item := range items {
+		var votes = map[int]float64{}
+		var numberOfVotes = 0
+		labelsOfAnnotations[i] = make([][]int, len(annotationNames))
+		for a, annotationName := range annotationNames {
+			for _, label := range item[annotationName] {
+				var labelId = getLabelId(label)
+				if _, ok := votes[labelId]; !ok {
+					labelsOfItems[i] = append(labelsOfItems[i], labelId)
+				}
+				votes[labelId]++
+				labelsOfAnnotations[i][a] = append(labelsOfAnnotations[i][a], labelId)
+				numberOfVotes++
+			}
+		}
+		posteriors[i] = make([]float64, len(labelsOfItems[i]))
+		for j, labelId := range labelsOfItems[i] {
+			posteriors[i][j] = votes[labelId] / float64(numberOfVotes)
+		}
+	}
+	var numberOfLabels = len(labels)
+
+	for iteration := 0; iteration < dawidSkeneMaxIterations; iteration++ {
+		// M-step: prior of every label and confusion of every annotation, weighted with the posteriors
+		var priors = make([]float64, numberOfLabels)
+		var confusions = make([]map[int]float64, len(annotationNames))
+		var confusionTotals = make([][]float64, len(annotationNames))
+		for a := range annotationNames {
+			confusions[a] = map[int]float64{}
+			confusionTotals[a] = make([]float64, numberOfLabels)
+		}
+		for i := range items {
+			for j, trueLabelId := range labelsOfItems[i] {
+				var posterior = posteriors[i][j]
+				priors[trueLabelId] += posterior
+				for a := range annotationNames {
+					for _, labelId := range labelsOfAnnotations[i][a] {
+						confusions[a][trueLabelId*numberOfLabels+labelId] += posterior
+						confusionTotals[a][trueLabelId] += posterior
+					}
+				}
+			}
+		}
+
+		// The logarithms are taken once per iteration. Labels an annotation never gave for a true label
+		// share the logarithm of the smoothing
+		var logPriors = make([]float64, numberOfLabels)
+		for labelId, prior := range priors {
+			logPriors[labelId] = math.Log((prior + dawidSkeneSmoothing) / (float64(len(items)) + dawidSkeneSmoothing*float64(numberOfLabels)))
+		}
+		var logConfusions = make([]map[int]float64, len(annotationNames))
+		var logSmoothings = make([][]float64, len(annotationNames))
+		for a := range annotationNames {
+			logConfusions[a] = make(map[int]float64, len(confusions[a]))
+			for key, confusion := range confusions[a] {
+				logConfusions[a][key] = math.Log((confusion + dawidSkeneSmoothing) / (confusionTotals[a][key/numberOfLabels] + dawidSkeneSmoothing*float64(numberOfLabels)))
+			}
+			logSmoothings[a] = make([]float64, numberOfLabels)
+			for trueLabelId, confusionTotal := range confusionTotals[a] {
+				logSmoothings[a][trueLabelId] = math.Log(dawidSkeneSmoothing / (confusionTotal + dawidSkeneSmoothing*float64(numberOfLabels)))
+			}
+		}
+
+		// E-step: posterior of every possible true label of an item, calculated in log space
+		var maximumChange = float64(0)
+		for i := range items {
+			var logLikelihoods = make([]float64, len(labelsOfItems[i]))
+			var maximumLogLikelihood = math.Inf(-1)
+			for j, trueLabelId := range labelsOfItems[i] {
+				logLikelihoods[j] = logPriors[trueLabelId]
+				for a := range annotationNames {
+					for _, labelId := range labelsOfAnnotations[i][a] {
+						if logConfusion, ok := logConfusions[a][trueLabelId*numberOfLabels+labelId]; ok {
+							logLikelihoods[j] += logConfusion
+						} else {
+							logLikelihoods[j] += logSmoothings[a][trueLabelId]
+						}
+					}
+				}
+				maximumLogLikelihood = math.Max(maximumLogLikelihood, logLikelihoods[j])
+			}
+			var sumOfLikelihoods = float64(0)
+			for j := range logLikelihoods {
+				logLikelihoods[j] = math.Exp(logLikelihoods[j] - maximumLogLikelihood)
+				sumOfLikelihoods += logLikelihoods[j]
+			}
+			for j := range labelsOfItems[i] {
+				var posterior = logLikelihoods[j] / sumOfLikelihoods
+				maximumChange = math.Max(maximumChange, math.Abs(posterior-posteriors[i][j]))
+				posteriors[i][j] = posterior
+			}
+		}
+		if maximumChange < dawidSkeneTolerance {
+			break
+		}
+	}
+
+	var posteriorsOfLabels = make([]map[string]float64, len(items))
+	for i := range items {
+		posteriorsOfLabels[i] = map[string]float64{}
+		for j, labelId := range labelsOfItems[i] {
+			posteriorsOfLabels[i][labels[labelId]] = posteriors[i][j]
+		}
+	}
+	return posteriorsOfLabels
+}
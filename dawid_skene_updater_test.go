@@ -0,0 +1,111 @@
+package main
+
+import (
+	"testing"
+)
+
+// B coded token 0 with an empty tore, C did not code it
+func TestGetLabelsOfTokensSeparatesUncodedFromEmptyLabels(t *testing.T) {
+	var codeAlternatives = []CodeAlternatives{
+		makeTestCodeAlternative(0, "A", "Pending", "Task", "x", 0),
+		makeTestCodeAlternative(1, "B", "Pending", "", "x", 0),
+	}
+	var tokenIndices, items = getLabelsOfTokens(codeAlternatives, []string{"A", "B", "C"}, func(code Code) string { return code.Tore })
+	if len(tokenIndices) != 1 || tokenIndices[0] != 0 {
+		t.Fatalf("tokenIndices = %v, want [0]", tokenIndices)
+	}
+	var want = map[string]string{"A": "Task", "B": "", "C": dawidSkeneUncodedLabel}
+	for annotationName, label := range want {
+		if labels := items[0][annotationName]; len(labels) != 1 || labels[0] != label {
+			t.Errorf("labels of %s = %q, want [%q]", annotationName, labels, label)
+		}
+	}
+}
+
+// The tore only has to be taken from most annotations, so it does not split the codes or lower the confidence
+func TestDawidSkeneTakesMajorityFieldsFromTheCandidate(t *testing.T) {
+	var codeAlternatives = []CodeAlternatives{
+		makeTestCodeAlternative(0, "A", "Pending", "Goal", "x", 0, 1),
+		makeTestCodeAlternative(1, "B", "Pending", "Task", "x", 0, 1),
+		makeTestCodeAlternative(2, "C", "Pending", "Task", "x", 0, 1),
+	}
+	var mergePolicy = getMergePolicy(mergePolicyMajority, mergePolicyMustMatch, mergePolicyMustMatch)
+	codeAlternatives, labelProposals := updateStatusOfCodeAlternativesWithDawidSkene(codeAlternatives, nil, []string{"A", "B", "C"}, 0.9, getSpanMatching(spanMatchingExact, 0, ""), mergePolicy)
+
+	if len(labelProposals) != 1 || !labelProposals[0].IsApplied || labelProposals[0].Tore != "Task" {
+		t.Fatalf("labelProposals = %+v, want one applied proposal with tore Task", labelProposals)
+	}
+	var numberOfAccepted = 0
+	for _, codeAlternative := range codeAlternatives {
+		if codeAlternative.MergeStatus == "Accepted" {
+			numberOfAccepted++
+			if codeAlternative.Code.Tore != "Task" {
+				t.Errorf("accepted tore = %s, want Task", codeAlternative.Code.Tore)
+			}
+		} else if codeAlternative.MergeStatus != "Declined" {
+			t.Errorf("status of %d = %s, want Declined", codeAlternative.Index, codeAlternative.MergeStatus)
+		}
+	}
+	if numberOfAccepted != 1 {
+		t.Errorf("%d codes accepted, want 1", numberOfAccepted)
+	}
+}
+
+// A tie of a majority field and a different span are recorded in the merge details of the accepted code
+func TestDawidSkeneSetsMergeDetails(t *testing.T) {
+	var codeAlternatives = []CodeAlternatives{
+		makeTestCodeAlternative(0, "A", "Pending", "Goal", "x", 0, 1),
+		makeTestCodeAlternative(1, "B", "Pending", "Task", "x", 1),
+	}
+	var mergePolicy = getMergePolicy(mergePolicyMajority, mergePolicyMustMatch, mergePolicyMustMatch)
+	codeAlternatives, _ = updateStatusOfCodeAlternativesWithDawidSkene(codeAlternatives, nil, []string{"A", "B"}, 0.5, getSpanMatching(spanMatchingHead, 0, ""), mergePolicy)
+
+	var acceptedIndex = -1
+	for i, codeAlternative := range codeAlternatives {
+		if codeAlternative.MergeStatus == "Accepted" {
+			acceptedIndex = i
+		}
+	}
+	if acceptedIndex == -1 {
+		t.Fatalf("no code accepted: %+v", codeAlternatives)
+	}
+	var mergeDetails = codeAlternatives[acceptedIndex].MergeDetails
+	if mergeDetails == nil {
+		t.Fatalf("accepted code has no merge details")
+	}
+	if mergeDetails.SpanMatchingStrategy != spanMatchingHead || len(mergeDetails.OtherSpans) != 1 {
+		t.Errorf("merge details = %+v, want one other span of head matching", mergeDetails)
+	}
+	if len(mergeDetails.FieldsToReview) != 1 || mergeDetails.FieldsToReview[0] != mergeFieldTore {
+		t.Errorf("fields to review = %v, want [%s]", mergeDetails.FieldsToReview, mergeFieldTore)
+	}
+}
+
+// The preview runs Dawid-Skene on a copy, so the code alternatives keep their status
+func TestGetMergePreviewsWithDawidSkene(t *testing.T) {
+	var codeAlternatives = []CodeAlternatives{
+		makeTestCodeAlternative(0, "A", "Pending", "Task", "x", 0),
+		makeTestCodeAlternative(1, "B", "Pending", "Task", "x", 0),
+		makeTestCodeAlternative(2, "C", "Pending", "Goal", "y", 0),
+	}
+	var mergePolicy = getMergePolicy(mergePolicyMustMatch, mergePolicyMustMatch, mergePolicyMustMatch)
+	var spanMatching = getSpanMatching(spanMatchingExact, 0, "")
+	var mergePreviews = getMergePreviews(codeAlternatives, nil, []string{"A", "B", "C"}, 3, spanMatching, mergePolicy, aggregationModeDawidSkene, 0.5)
+
+	var wantStatuses = []string{"Accepted", "Declined", "Declined"}
+	for i, mergePreview := range mergePreviews {
+		if mergePreview.MergeStatus != wantStatuses[i] {
+			t.Errorf("preview status of %d = %s, want %s", i, mergePreview.MergeStatus, wantStatuses[i])
+		}
+		if codeAlternatives[i].MergeStatus != "Pending" {
+			t.Errorf("status of %d = %s, want Pending", i, codeAlternatives[i].MergeStatus)
+		}
+	}
+	// The majority merge needs all 3 annotations and accepts nothing
+	mergePreviews = getMergePreviews(codeAlternatives, nil, []string{"A", "B", "C"}, 3, spanMatching, mergePolicy, "", 0.5)
+	for i, mergePreview := range mergePreviews {
+		if mergePreview.MergeStatus != "Pending" {
+			t.Errorf("majority preview status of %d = %s, want Pending", i, mergePreview.MergeStatus)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"sort"
+)
+
+// DocumentAgreement model, the kappas of the tokens of a single document
+type DocumentAgreement struct {
+	Name                string             `json:"name"`
+	BeginIndex          int                `json:"begin_index"`
+	EndIndex            int                `json:"end_index"`
+	NumberOfCodedTokens int                `json:"number_of_coded_tokens"`
+	Kappas              map[string]float64 `json:"kappas"`
+}
+
+// Returns the kappas of all documents that contain codes, ranked from the lowest to the highest fleiss kappa
+func getDocumentAgreements(
+	agreement Agreement,
+	toreCategories ToreCategories,
+	toreRelationships ToreRelationships,
+) []DocumentAgreement {
+	var documentAgreements []DocumentAgreement
+	for _, doc := range agreement.Docs {
+		if doc.BeginIndex == nil || doc.EndIndex == nil {
+			continue
+		}
+		var documentAgreement = restrictAgreementToTokenRange(agreement, *doc.BeginIndex, *doc.EndIndex)
+		var numberOfCodedTokens = len(groupCodeAlternativesByToken(documentAgreement.CodeAlternatives))
+		if numberOfCodedTokens == 0 {
+			continue
+		}
+		documentAgreements = append(documentAgreements, DocumentAgreement{
+			Name:                doc.Name,
+			BeginIndex:          *doc.BeginIndex,
+			EndIndex:            *doc.EndIndex,
+			NumberOfCodedTokens: numberOfCodedTokens,
+			Kappas:              getKappas(documentAgreement, toreCategories, toreRelationships),
+		})
+	}
+	sort.SliceStable(documentAgreements, func(i, j int) bool {
+		return documentAgreements[i].Kappas[fleissKappaName] < documentAgreements[j].Kappas[fleissKappaName]
+	})
+	return documentAgreements
+}
+
+// Returns a copy of the agreement, that only contains the tokens from beginIndex (inclusive) to endIndex (exclusive)
+// Codes are cut to these tokens, codes without any token left are removed
+func restrictAgreementToTokenRange(agreement Agreement, beginIndex int, endIndex int) Agreement {
+	var restrictedAgreement = agreement
+	restrictedAgreement.Tokens = []Token{}
+	restrictedAgreement.CodeAlternatives = []CodeAlternatives{}
+
+	for _, token := range agreement.Tokens {
+		if *token.Index >= beginIndex && *token.Index < endIndex {
+			restrictedAgreement.Tokens = append(restrictedAgreement.Tokens, token)
+		}
+	}
+	for _, codeAlternative := range agreement.CodeAlternatives {
+		var tokensInRange []*int
+		for _, token := range codeAlternative.Code.Tokens {
+			if *token >= beginIndex && *token < endIndex {
+				tokensInRange = append(tokensInRange, token)
+			}
+		}
+		if len(tokensInRange) == 0 {
+			continue
+		}
+		codeAlternative.Code.Tokens = tokensInRange
+		restrictedAgreement.CodeAlternatives = append(restrictedAgreement.CodeAlternatives, codeAlternative)
+	}
+	return restrictedAgreement
+}
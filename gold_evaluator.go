@@ -0,0 +1,134 @@
+package main
+
+// PrecisionRecallF1 model, the counts are the number of matched, annotated and accepted items
+type PrecisionRecallF1 struct {
+	Precision         float64 `json:"precision"`
+	Recall            float64 `json:"recall"`
+	F1                float64 `json:"f1"`
+	NumberOfMatches   int     `json:"number_of_matches"`
+	NumberOfAnnotated int     `json:"number_of_annotated"`
+	NumberOfAccepted  int     `json:"number_of_accepted"`
+}
+
+// AnnotatorQuality model, the codes and relationships of an annotation compared with the accepted ones of the agreement
+type AnnotatorQuality struct {
+	AnnotationName    string                       `json:"annotation_name"`
+	Tore              PrecisionRecallF1            `json:"tore"`
+	ToreCategories    map[string]PrecisionRecallF1 `json:"tore_categories"`
+	WordCode          PrecisionRecallF1            `json:"word_code"`
+	Relationship      PrecisionRecallF1            `json:"relationship"`
+	RelationshipNames map[string]PrecisionRecallF1 `json:"relationship_names"`
+}
+
+// Compares every annotation of the agreement with the accepted code alternatives
+// All codes of an annotation are used, whatever their merge status, because they are what the annotator did
+func getAnnotatorQualities(agreement Agreement, toreCategories ToreCategories) []AnnotatorQuality {
+	var relationshipMap = createRelationshipMap(agreement)
+
+	var acceptedCodes []Code
+	var codesOfAnnotations = map[string][]Code{}
+	for _, codeAlternative := range agreement.CodeAlternatives {
+		codesOfAnnotations[codeAlternative.AnnotationName] = append(codesOfAnnotations[codeAlternative.AnnotationName], codeAlternative.Code)
+		if codeAlternative.MergeStatus == "Accepted" {
+			acceptedCodes = append(acceptedCodes, codeAlternative.Code)
+		}
+	}
+	var acceptedArcs = getArcsOfCodes(acceptedCodes, relationshipMap)
+
+	var relationshipNameSet = map[string]bool{}
+	for _, toreRelationship := range agreement.TORERelationships {
+		if toreRelationship.RelationshipName != "" {
+			relationshipNameSet[toreRelationship.RelationshipName] = true
+		}
+	}
+
+	var annotatorQualities = []AnnotatorQuality{}
+	for _, annotationName := range agreement.Annotations {
+		var codes = codesOfAnnotations[annotationName]
+		var arcs = getArcsOfCodes(codes, relationshipMap)
+
+		var annotatorQuality = AnnotatorQuality{
+			AnnotationName:    annotationName,
+			Tore:              calculatePrecisionRecallF1(getToreKeys(codes, ""), getToreKeys(acceptedCodes, "")),
+			ToreCategories:    map[string]PrecisionRecallF1{},
+			WordCode:          calculatePrecisionRecallF1(getWordCodeKeys(codes), getWordCodeKeys(acceptedCodes)),
+			Relationship:      calculatePrecisionRecallF1(getArcKeys(arcs, ""), getArcKeys(acceptedArcs, "")),
+			RelationshipNames: map[string]PrecisionRecallF1{},
+		}
+		for _, toreCategory := range toreCategories.Tores {
+			annotatorQuality.ToreCategories[toreCategory] = calculatePrecisionRecallF1(getToreKeys(codes, toreCategory), getToreKeys(acceptedCodes, toreCategory))
+		}
+		for relationshipName := range relationshipNameSet {
+			annotatorQuality.RelationshipNames[relationshipName] = calculatePrecisionRecallF1(getArcKeys(arcs, relationshipName), getArcKeys(acceptedArcs, relationshipName))
+		}
+		annotatorQualities = append(annotatorQualities, annotatorQuality)
+	}
+	return annotatorQualities
+}
+
+// Returns a key of span and tore for every code with a tore, only of the given category if it is not ""
+func getToreKeys(codes []Code, toreCategory string) []string {
+	var keys []string
+	for _, code := range codes {
+		if code.Tore == "" || (toreCategory != "" && code.Tore != toreCategory) {
+			continue
+		}
+		keys = append(keys, getSpanKey(code.Tokens)+code.Tore)
+	}
+	return keys
+}
+
+// Returns a key of span and name for every code with a name
+func getWordCodeKeys(codes []Code) []string {
+	var keys []string
+	for _, code := range codes {
+		if code.Name == "" {
+			continue
+		}
+		keys = append(keys, getSpanKey(code.Tokens)+code.Name)
+	}
+	return keys
+}
+
+// Returns a key for every arc, only of the given relationship if it is not ""
+func getArcKeys(arcs []relationshipArc, relationshipName string) []string {
+	var keys []string
+	for _, arc := range arcs {
+		if relationshipName != "" && arc.relationshipName != relationshipName {
+			continue
+		}
+		keys = append(keys, arc.sourceKey+"-"+arc.relationshipName+"->"+arc.targetKey)
+	}
+	return keys
+}
+
+// Every accepted item can only be matched once
+func calculatePrecisionRecallF1(annotatedKeys []string, acceptedKeys []string) PrecisionRecallF1 {
+	var result = PrecisionRecallF1{
+		NumberOfAnnotated: len(annotatedKeys),
+		NumberOfAccepted:  len(acceptedKeys),
+	}
+	// This is only for the special case, when there is nothing to compare
+	if len(annotatedKeys) == 0 && len(acceptedKeys) == 0 {
+		result.Precision, result.Recall, result.F1 = 1.0, 1.0, 1.0
+		return result
+	}
+	var acceptedCounts = map[string]int{}
+	for _, key := range acceptedKeys {
+		acceptedCounts[key]++
+	}
+	for _, key := range annotatedKeys {
+		if acceptedCounts[key] > 0 {
+			acceptedCounts[key]--
+			result.NumberOfMatches++
+		}
+	}
+	if len(annotatedKeys) != 0 {
+		result.Precision = float64(result.NumberOfMatches) / float64(len(annotatedKeys))
+	}
+	if len(acceptedKeys) != 0 {
+		result.Recall = float64(result.NumberOfMatches) / float64(len(acceptedKeys))
+	}
+	result.F1 = float64(2*result.NumberOfMatches) / float64(len(annotatedKeys)+len(acceptedKeys))
+	return result
+}
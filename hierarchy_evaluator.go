@@ -0,0 +1,73 @@
+package main
+
+// Aggregation levels of the tores of codes
+const (
+	aggregationLevelCategory  = "category"
+	aggregationLevelToreLevel = "tore_level"
+)
+
+// ToreLevelMapping maps a tore category to its level of abstraction
+type ToreLevelMapping map[string]string
+
+// Used if neither the request nor the configuration contain a mapping
+var defaultToreLevelMapping = ToreLevelMapping{
+	"Stakeholder":          "Domain",
+	"Persona":              "Domain",
+	"Goal":                 "Domain",
+	"Task":                 "Domain",
+	"Activity":             "Domain",
+	"Interaction":          "Interaction",
+	"System Function":      "Interaction",
+	"Workspace":            "Interaction",
+	"Software":             "System",
+	"External Application": "System",
+	"Feature":              "System",
+	"Domain Data":          "Data",
+	"Interaction Data":     "Data",
+}
+
+// Returns the mapping from the request, or the configured one, or the default one
+func getToreLevelMapping(requestMapping ToreLevelMapping) (ToreLevelMapping, error) {
+	if len(requestMapping) != 0 {
+		return requestMapping, nil
+	}
+	var mapping ToreLevelMapping
+	err := readConfigurationFile(toreLevelMappingFile, &mapping)
+	if err != nil || len(mapping) == 0 {
+		return defaultToreLevelMapping, err
+	}
+	return mapping, nil
+}
+
+// Returns the fleiss kappa and krippendorff alpha over the tores of the codes, aggregated to aggregationLevel
+// Tores without a level in toreLevelMapping stay a level of their own. Only the levels of codes are compared,
+// annotations without a code on a token are no level, as they would agree with each other on sparse tokens
+func getAggregatedKappas(
+	agreement Agreement,
+	aggregationLevel string,
+	toreLevelMapping ToreLevelMapping,
+) map[string]float64 {
+	var getCategory = func(tore string) string { return tore }
+	if aggregationLevel == aggregationLevelToreLevel {
+		getCategory = func(tore string) string {
+			if level, ok := toreLevelMapping[tore]; ok {
+				return level
+			}
+			return tore
+		}
+	}
+
+	// Without distances every pair of different categories is a full disagreement
+	_, reliabilityCategoryRows := getCategoryRows(agreement, getCategory)
+	var kappas = map[string]float64{
+		fleissKappaName:       calculateWeightedFleissKappa(reliabilityCategoryRows, ToreCategoryDistances{}),
+		krippendorffAlphaName: calculateWeightedKrippendorffAlpha(reliabilityCategoryRows, ToreCategoryDistances{}),
+	}
+	// Special cases, when Kappas are either smaller than 0 or None because some denominator is 0
+	for kappaName, kappa := range kappas {
+		if (kappa < 0) || (kappa != kappa) {
+			kappas[kappaName] = 0.0
+		}
+	}
+	return kappas
+}
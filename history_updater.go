@@ -0,0 +1,136 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// KappaHistoryFields Used to group the agreement fields that change with a new kappa snapshot
+type KappaHistoryFields struct {
+	KappaHistory        []KappaSnapshot       `json:"kappa_history" bson:"kappa_history"`
+	AgreementStatistics []AgreementStatistics `json:"agreement_statistics" bson:"agreement_statistics"`
+}
+
+// KappaSeries model, the kappa history as one series per kappa name
+type KappaSeries struct {
+	Timestamps        []time.Time          `json:"timestamps"`
+	NumberOfDecisions []int                `json:"number_of_decisions"`
+	Kappas            map[string][]float64 `json:"kappas"`
+}
+
+// Appends a snapshot of the current kappas to the history and updates the agreement statistics
+// Nothing is appended, if neither the number of decisions nor the kappas changed since the last snapshot
+func updateKappaHistory(
+	agreement Agreement,
+	toreCategories ToreCategories,
+	toreRelationships ToreRelationships,
+) KappaHistoryFields {
+	var snapshot = KappaSnapshot{
+		CreatedAt:         time.Now(),
+		NumberOfDecisions: countDecisions(agreement.CodeAlternatives),
+		Kappas:            getKappas(agreement, toreCategories, toreRelationships),
+	}
+
+	var kappaHistory = agreement.KappaHistory
+	if len(kappaHistory) == 0 || !testSnapshotsAreEqual(kappaHistory[len(kappaHistory)-1], snapshot) {
+		kappaHistory = append(kappaHistory, snapshot)
+	}
+
+	return KappaHistoryFields{
+		KappaHistory:        kappaHistory,
+		AgreementStatistics: updateAgreementStatistics(agreement.AgreementStatistics, kappaHistory),
+	}
+}
+
+// Sets InitialKappa to the first and CurrentKappa to the last snapshot, statistics are added for new kappa names
+// Existing statistics keep their InitialKappa, unless the history had no snapshot before the current one
+func updateAgreementStatistics(
+	agreementStatistics []AgreementStatistics,
+	kappaHistory []KappaSnapshot,
+) []AgreementStatistics {
+	var initialKappas = kappaHistory[0].Kappas
+	var currentKappas = kappaHistory[len(kappaHistory)-1].Kappas
+	var updatedStatistics []AgreementStatistics
+	var kappaNameSet = map[string]bool{}
+	for _, statistics := range agreementStatistics {
+		if currentKappa, ok := currentKappas[statistics.KappaName]; ok {
+			statistics.CurrentKappa = currentKappa
+			if len(kappaHistory) == 1 {
+				statistics.InitialKappa = currentKappa
+			}
+		}
+		kappaNameSet[statistics.KappaName] = true
+		updatedStatistics = append(updatedStatistics, statistics)
+	}
+	for _, kappaName := range getSortedKappaNames(currentKappas) {
+		if kappaNameSet[kappaName] {
+			continue
+		}
+		var initialKappa, ok = initialKappas[kappaName]
+		if !ok {
+			initialKappa = currentKappas[kappaName]
+		}
+		updatedStatistics = append(updatedStatistics, AgreementStatistics{
+			KappaName:    kappaName,
+			InitialKappa: initialKappa,
+			CurrentKappa: currentKappas[kappaName],
+		})
+	}
+	return updatedStatistics
+}
+
+// Returns the history as one series per kappa name, snapshots without a kappa get 0
+func getKappaSeries(kappaHistory []KappaSnapshot) KappaSeries {
+	var kappaSeries = KappaSeries{
+		Timestamps:        []time.Time{},
+		NumberOfDecisions: []int{},
+		Kappas:            map[string][]float64{},
+	}
+	for _, snapshot := range kappaHistory {
+		for kappaName := range snapshot.Kappas {
+			if _, ok := kappaSeries.Kappas[kappaName]; !ok {
+				kappaSeries.Kappas[kappaName] = []float64{}
+			}
+		}
+	}
+	for _, snapshot := range kappaHistory {
+		kappaSeries.Timestamps = append(kappaSeries.Timestamps, snapshot.CreatedAt)
+		kappaSeries.NumberOfDecisions = append(kappaSeries.NumberOfDecisions, snapshot.NumberOfDecisions)
+		for kappaName := range kappaSeries.Kappas {
+			kappaSeries.Kappas[kappaName] = append(kappaSeries.Kappas[kappaName], snapshot.Kappas[kappaName])
+		}
+	}
+	return kappaSeries
+}
+
+// Returns the number of code alternatives that are accepted or declined
+func countDecisions(codeAlternatives []CodeAlternatives) int {
+	var numberOfDecisions = 0
+	for _, codeAlternative := range codeAlternatives {
+		if codeAlternative.MergeStatus == "Accepted" || codeAlternative.MergeStatus == "Declined" {
+			numberOfDecisions++
+		}
+	}
+	return numberOfDecisions
+}
+
+func testSnapshotsAreEqual(a KappaSnapshot, b KappaSnapshot) bool {
+	if a.NumberOfDecisions != b.NumberOfDecisions || len(a.Kappas) != len(b.Kappas) {
+		return false
+	}
+	for kappaName, kappa := range a.Kappas {
+		if otherKappa, ok := b.Kappas[kappaName]; !ok || otherKappa != kappa {
+			return false
+		}
+	}
+	return true
+}
+
+func getSortedKappaNames(kappas map[string]float64) []string {
+	var kappaNames []string
+	for kappaName := range kappas {
+		kappaNames = append(kappaNames, kappaName)
+	}
+	sort.Strings(kappaNames)
+	return kappaNames
+}
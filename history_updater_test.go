@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+)
+
+// The agreement already has statistics, but no history. The first snapshot sets both kappas of the statistics,
+// later snapshots only the current kappa
+func TestUpdateAgreementStatisticsSetsInitialKappaOfFirstSnapshot(t *testing.T) {
+	var agreementStatistics = []AgreementStatistics{
+		{KappaName: fleissKappaName, InitialKappa: 0, CurrentKappa: 0},
+	}
+	var firstSnapshot = KappaSnapshot{NumberOfDecisions: 0, Kappas: map[string]float64{fleissKappaName: 0.4}}
+	var secondSnapshot = KappaSnapshot{NumberOfDecisions: 1, Kappas: map[string]float64{fleissKappaName: 0.6}}
+
+	agreementStatistics = updateAgreementStatistics(agreementStatistics, []KappaSnapshot{firstSnapshot})
+	testFloatsAreEqual(t, "InitialKappa after first snapshot", agreementStatistics[0].InitialKappa, 0.4)
+	testFloatsAreEqual(t, "CurrentKappa after first snapshot", agreementStatistics[0].CurrentKappa, 0.4)
+
+	agreementStatistics = updateAgreementStatistics(agreementStatistics, []KappaSnapshot{firstSnapshot, secondSnapshot})
+	testFloatsAreEqual(t, "InitialKappa after second snapshot", agreementStatistics[0].InitialKappa, 0.4)
+	testFloatsAreEqual(t, "CurrentKappa after second snapshot", agreementStatistics[0].CurrentKappa, 0.6)
+}
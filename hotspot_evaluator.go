@@ -0,0 +1,99 @@
+package main
+
+import (
+	"sort"
+)
+
+// DisagreementHotspot model, a token or a span of neighbouring tokens on which the annotations disagree
+// Agreement is the mean share of agreeing pairs of codes of the tokens
+type DisagreementHotspot struct {
+	TokenIndices               []int              `json:"token_indices"`
+	Agreement                  float64            `json:"agreement"`
+	CodeAlternatives           []CodeAlternatives `json:"code_alternatives"`
+	AnnotationNames            []string           `json:"annotation_names"`
+	AnnotationNamesWithoutCode []string           `json:"annotation_names_without_code"`
+}
+
+// Returns all tokens without full agreement, ranked from the lowest to the highest agreement
+// If groupSpans is set, neighbouring tokens are grouped into one hotspot. A limit of 0 returns all hotspots
+func getDisagreementHotspots(
+	agreement Agreement,
+	toreCategories ToreCategories,
+	toreRelationships ToreRelationships,
+	groupSpans bool,
+	limit int,
+) []DisagreementHotspot {
+	_, dataMatrixForRowCalculation, _ := getDataMatrices(agreement, toreCategories, toreRelationships)
+	var tokenMap = groupCodeAlternativesByToken(agreement.CodeAlternatives)
+
+	// Collect the tokens without full agreement, a span is continued as long as the next token follows directly
+	var spans [][]int
+	var agreementsOfSpans [][]float64
+	var previousTokenIndex = -1
+	for row, tokenIndex := range getCodedTokenIndices(agreement) {
+		var tokenAgreement = calculateTokenAgreement(dataMatrixForRowCalculation[row])
+		if tokenAgreement >= 1.0 || tokenAgreement != tokenAgreement {
+			continue
+		}
+		if groupSpans && len(spans) != 0 && tokenIndex == previousTokenIndex+1 {
+			spans[len(spans)-1] = append(spans[len(spans)-1], tokenIndex)
+			agreementsOfSpans[len(agreementsOfSpans)-1] = append(agreementsOfSpans[len(agreementsOfSpans)-1], tokenAgreement)
+		} else {
+			spans = append(spans, []int{tokenIndex})
+			agreementsOfSpans = append(agreementsOfSpans, []float64{tokenAgreement})
+		}
+		previousTokenIndex = tokenIndex
+	}
+
+	var hotspots = []DisagreementHotspot{}
+	for i, span := range spans {
+		var sumOfAgreements = float64(0)
+		for _, tokenAgreement := range agreementsOfSpans[i] {
+			sumOfAgreements += tokenAgreement
+		}
+		hotspots = append(hotspots, makeDisagreementHotspot(span, sumOfAgreements/float64(len(span)), tokenMap, agreement.Annotations))
+	}
+	sort.SliceStable(hotspots, func(i, j int) bool {
+		return hotspots[i].Agreement < hotspots[j].Agreement
+	})
+	if limit > 0 && len(hotspots) > limit {
+		hotspots = hotspots[:limit]
+	}
+	return hotspots
+}
+
+// Collects the competing code alternatives on the tokens of a hotspot and the annotations they come from
+func makeDisagreementHotspot(
+	tokenIndices []int,
+	spanAgreement float64,
+	tokenMap map[int][]CodeAlternatives,
+	annotationNames []string,
+) DisagreementHotspot {
+	var hotspot = DisagreementHotspot{
+		TokenIndices:               tokenIndices,
+		Agreement:                  spanAgreement,
+		CodeAlternatives:           []CodeAlternatives{},
+		AnnotationNames:            []string{},
+		AnnotationNamesWithoutCode: []string{},
+	}
+	var alternativeSet = map[int]bool{}
+	var annotationNameSet = map[string]bool{}
+	for _, tokenIndex := range tokenIndices {
+		for _, codeAlternative := range tokenMap[tokenIndex] {
+			if alternativeSet[codeAlternative.Index] {
+				continue
+			}
+			alternativeSet[codeAlternative.Index] = true
+			hotspot.CodeAlternatives = append(hotspot.CodeAlternatives, codeAlternative)
+			annotationNameSet[codeAlternative.AnnotationName] = true
+		}
+	}
+	for _, annotationName := range annotationNames {
+		if annotationNameSet[annotationName] {
+			hotspot.AnnotationNames = append(hotspot.AnnotationNames, annotationName)
+		} else {
+			hotspot.AnnotationNamesWithoutCode = append(hotspot.AnnotationNamesWithoutCode, annotationName)
+		}
+	}
+	return hotspot
+}
@@ -0,0 +1,119 @@
+package main
+
+// AnnotationPair model, two annotations of the same dataset by the same annotator in different rounds
+type AnnotationPair struct {
+	Annotator            string `json:"annotator"`
+	FirstAnnotationName  string `json:"first_annotation_name"`
+	SecondAnnotationName string `json:"second_annotation_name"`
+}
+
+// IntraRaterAgreement model, the test-retest agreement of an annotator between two rounds
+type IntraRaterAgreement struct {
+	AnnotationPair
+
+	Kappas        map[string]float64 `json:"kappas"`
+	CohenKappa    float64            `json:"cohen_kappa"`
+	SpanAgreement SpanAgreement      `json:"span_agreement"`
+}
+
+// AnnotatorIntraRaterAgreement model, the means of the intra-rater agreements of all pairs of an annotator
+type AnnotatorIntraRaterAgreement struct {
+	Annotator     string             `json:"annotator"`
+	NumberOfPairs int                `json:"number_of_pairs"`
+	Kappas        map[string]float64 `json:"kappas"`
+	CohenKappa    float64            `json:"cohen_kappa"`
+	ToreExactF1   float64            `json:"tore_exact_f1"`
+	ToreOverlapF1 float64            `json:"tore_overlap_f1"`
+	NameExactF1   float64            `json:"name_exact_f1"`
+	NameOverlapF1 float64            `json:"name_overlap_f1"`
+}
+
+// IntraRaterReliability model, the intra-rater agreements of all pairs and of all annotators
+type IntraRaterReliability struct {
+	Pairs      []IntraRaterAgreement          `json:"pairs"`
+	Annotators []AnnotatorIntraRaterAgreement `json:"annotators"`
+}
+
+// Makes an agreement from both annotations of the pair, as for a new agreement, and calculates its kappas
+// The annotations have to be in the order of the pair
+func getIntraRaterAgreement(
+	annotationPair AnnotationPair,
+	annotations []Annotation,
+	toreCategories ToreCategories,
+	toreRelationships ToreRelationships,
+) IntraRaterAgreement {
+	var annotationNames = []string{annotationPair.FirstAnnotationName, annotationPair.SecondAnnotationName}
+	docs, tokens, relationships, codeAlternatives := initializeInfoFromFetchedAnnotations(annotationNames, annotations)
+	var agreement = Agreement{
+		Annotations:       annotationNames,
+		Docs:              docs,
+		Tokens:            tokens,
+		TORERelationships: relationships,
+		CodeAlternatives:  codeAlternatives,
+	}
+
+	return IntraRaterAgreement{
+		AnnotationPair: annotationPair,
+		Kappas:         getKappas(agreement, toreCategories, toreRelationships),
+		CohenKappa:     getCohenKappaMatrix(agreement, toreCategories, toreRelationships).Kappas[0][1],
+		SpanAgreement:  getSpanAgreements(agreement, defaultSpanIoUThreshold).Pairs[0],
+	}
+}
+
+// Returns the mean agreement of every annotator over all of their pairs, in the order of the first pair of the annotator
+func getAnnotatorIntraRaterAgreements(intraRaterAgreements []IntraRaterAgreement) []AnnotatorIntraRaterAgreement {
+	var annotatorAgreements = []AnnotatorIntraRaterAgreement{}
+	var positionOfAnnotator = map[string]int{}
+	for _, intraRaterAgreement := range intraRaterAgreements {
+		position, ok := positionOfAnnotator[intraRaterAgreement.Annotator]
+		if !ok {
+			position = len(annotatorAgreements)
+			positionOfAnnotator[intraRaterAgreement.Annotator] = position
+			annotatorAgreements = append(annotatorAgreements, AnnotatorIntraRaterAgreement{
+				Annotator: intraRaterAgreement.Annotator,
+				Kappas:    map[string]float64{},
+			})
+		}
+		var annotatorAgreement = &annotatorAgreements[position]
+		annotatorAgreement.NumberOfPairs++
+		for kappaName, kappa := range intraRaterAgreement.Kappas {
+			annotatorAgreement.Kappas[kappaName] += kappa
+		}
+		annotatorAgreement.CohenKappa += intraRaterAgreement.CohenKappa
+		annotatorAgreement.ToreExactF1 += intraRaterAgreement.SpanAgreement.ToreExactF1
+		annotatorAgreement.ToreOverlapF1 += intraRaterAgreement.SpanAgreement.ToreOverlapF1
+		annotatorAgreement.NameExactF1 += intraRaterAgreement.SpanAgreement.NameExactF1
+		annotatorAgreement.NameOverlapF1 += intraRaterAgreement.SpanAgreement.NameOverlapF1
+	}
+	for i := range annotatorAgreements {
+		var numberOfPairs = float64(annotatorAgreements[i].NumberOfPairs)
+		for kappaName := range annotatorAgreements[i].Kappas {
+			annotatorAgreements[i].Kappas[kappaName] /= numberOfPairs
+		}
+		annotatorAgreements[i].CohenKappa /= numberOfPairs
+		annotatorAgreements[i].ToreExactF1 /= numberOfPairs
+		annotatorAgreements[i].ToreOverlapF1 /= numberOfPairs
+		annotatorAgreements[i].NameExactF1 /= numberOfPairs
+		annotatorAgreements[i].NameOverlapF1 /= numberOfPairs
+	}
+	return annotatorAgreements
+}
+
+// Returns whether both annotations have the same tokens, in the same order
+func testAnnotationsHaveSameTokens(first Annotation, second Annotation) bool {
+	if len(first.Tokens) != len(second.Tokens) {
+		return false
+	}
+	for i := range first.Tokens {
+		if first.Tokens[i].Name != second.Tokens[i].Name {
+			return false
+		}
+		if (first.Tokens[i].Index == nil) != (second.Tokens[i].Index == nil) {
+			return false
+		}
+		if first.Tokens[i].Index != nil && *first.Tokens[i].Index != *second.Tokens[i].Index {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+)
+
+// Two pairs of A are averaged, the single pair of B is kept as it is
+func TestGetAnnotatorIntraRaterAgreementsAveragesPairsOfAnnotator(t *testing.T) {
+	var intraRaterAgreements = []IntraRaterAgreement{
+		{
+			AnnotationPair: AnnotationPair{Annotator: "A", FirstAnnotationName: "A1", SecondAnnotationName: "A2"},
+			Kappas:         map[string]float64{fleissKappaName: 0.2},
+			CohenKappa:     0.3,
+			SpanAgreement:  SpanAgreement{ToreExactF1: 0.5},
+		},
+		{
+			AnnotationPair: AnnotationPair{Annotator: "B", FirstAnnotationName: "B1", SecondAnnotationName: "B2"},
+			Kappas:         map[string]float64{fleissKappaName: 0.9},
+			CohenKappa:     0.8,
+			SpanAgreement:  SpanAgreement{ToreExactF1: 1},
+		},
+		{
+			AnnotationPair: AnnotationPair{Annotator: "A", FirstAnnotationName: "A2", SecondAnnotationName: "A3"},
+			Kappas:         map[string]float64{fleissKappaName: 0.6},
+			CohenKappa:     0.5,
+			SpanAgreement:  SpanAgreement{ToreExactF1: 0.7},
+		},
+	}
+	var annotatorAgreements = getAnnotatorIntraRaterAgreements(intraRaterAgreements)
+	if len(annotatorAgreements) != 2 || annotatorAgreements[0].Annotator != "A" || annotatorAgreements[1].Annotator != "B" {
+		t.Fatalf("annotatorAgreements = %v, want A and B", annotatorAgreements)
+	}
+	if annotatorAgreements[0].NumberOfPairs != 2 || annotatorAgreements[1].NumberOfPairs != 1 {
+		t.Errorf("NumberOfPairs = %d, %d, want 2, 1", annotatorAgreements[0].NumberOfPairs, annotatorAgreements[1].NumberOfPairs)
+	}
+	testFloatsAreEqual(t, "fleissKappa of A", annotatorAgreements[0].Kappas[fleissKappaName], 0.4)
+	testFloatsAreEqual(t, "cohenKappa of A", annotatorAgreements[0].CohenKappa, 0.4)
+	testFloatsAreEqual(t, "toreExactF1 of A", annotatorAgreements[0].ToreExactF1, 0.6)
+	testFloatsAreEqual(t, "fleissKappa of B", annotatorAgreements[1].Kappas[fleissKappaName], 0.9)
+}
+
+func TestAnnotationsHaveSameTokens(t *testing.T) {
+	var tokens = makeTestTokens(3)
+	var otherTokens = makeTestTokens(3)
+	otherTokens[1].Name = "other"
+	var tests = []struct {
+		name   string
+		first  []Token
+		second []Token
+		want   bool
+	}{
+		{"same tokens", tokens, makeTestTokens(3), true},
+		{"fewer tokens", tokens, makeTestTokens(2), false},
+		{"other name", tokens, otherTokens, false},
+	}
+	for _, test := range tests {
+		if got := testAnnotationsHaveSameTokens(Annotation{Tokens: test.first}, Annotation{Tokens: test.second}); got != test.want {
+			t.Errorf("%s: testAnnotationsHaveSameTokens = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+// AnnotatorKappaDelta model, the kappas without an annotation and their difference to the kappas with all annotations
+// A positive delta means that the agreement is higher without the annotation
+type AnnotatorKappaDelta struct {
+	AnnotationName string             `json:"annotation_name"`
+	Kappas         map[string]float64 `json:"kappas"`
+	Deltas         map[string]float64 `json:"deltas"`
+}
+
+// Recalculates the kappas with every annotation removed in turn
+func getLeaveOneAnnotatorOutKappas(
+	agreement Agreement,
+	toreCategories ToreCategories,
+	toreRelationships ToreRelationships,
+	kappas map[string]float64,
+) []AnnotatorKappaDelta {
+	var annotatorKappaDeltas = []AnnotatorKappaDelta{}
+	for _, annotationName := range agreement.Annotations {
+		var kappasWithoutAnnotation = getKappas(removeAnnotationFromAgreement(agreement, annotationName), toreCategories, toreRelationships)
+		var deltas = map[string]float64{}
+		for kappaName, kappa := range kappasWithoutAnnotation {
+			deltas[kappaName] = kappa - kappas[kappaName]
+		}
+		annotatorKappaDeltas = append(annotatorKappaDeltas, AnnotatorKappaDelta{
+			AnnotationName: annotationName,
+			Kappas:         kappasWithoutAnnotation,
+			Deltas:         deltas,
+		})
+	}
+	return annotatorKappaDeltas
+}
+
+// Returns a copy of the agreement without the annotation and its code alternatives
+// The declined code alternatives of the other annotations on the tokens of a removed accepted code alternative would
+// be lost with it, so an identical one is accepted instead and the others are pending again
+func removeAnnotationFromAgreement(agreement Agreement, annotationName string) Agreement {
+	var reducedAgreement = agreement
+	reducedAgreement.Annotations = []string{}
+	reducedAgreement.CodeAlternatives = []CodeAlternatives{}
+	for _, name := range agreement.Annotations {
+		if name != annotationName {
+			reducedAgreement.Annotations = append(reducedAgreement.Annotations, name)
+		}
+	}
+
+	var relationshipMap = createRelationshipMap(agreement)
+	var numberOfRemovedAcceptedCodes = map[string]int{}
+	var tokensOfRemovedAcceptedCodes = map[int]bool{}
+	for _, codeAlternative := range agreement.CodeAlternatives {
+		if codeAlternative.AnnotationName == annotationName && codeAlternative.MergeStatus == "Accepted" {
+			numberOfRemovedAcceptedCodes[getCodeKey(codeAlternative.Code, relationshipMap)]++
+			for _, token := range codeAlternative.Code.Tokens {
+				tokensOfRemovedAcceptedCodes[*token] = true
+			}
+		}
+	}
+	for _, codeAlternative := range agreement.CodeAlternatives {
+		if codeAlternative.AnnotationName == annotationName {
+			continue
+		}
+		if codeAlternative.MergeStatus == "Declined" {
+			var codeKey = getCodeKey(codeAlternative.Code, relationshipMap)
+			if numberOfRemovedAcceptedCodes[codeKey] > 0 {
+				numberOfRemovedAcceptedCodes[codeKey]--
+				codeAlternative.MergeStatus = "Accepted"
+			} else {
+				for _, token := range codeAlternative.Code.Tokens {
+					if tokensOfRemovedAcceptedCodes[*token] {
+						codeAlternative.MergeStatus = "Pending"
+						break
+					}
+				}
+			}
+		}
+		reducedAgreement.CodeAlternatives = append(reducedAgreement.CodeAlternatives, codeAlternative)
+	}
+	return reducedAgreement
+}
+
+// Returns a key for the span, tore, name and relationships of a code
+func getCodeKey(code Code, relationshipMap map[int]TORERelationship) string {
+	return getSpanKey(code.Tokens) + "|" + code.Tore + "|" + code.Name + "|" + getRelationshipsKey(code.RelationshipMemberships, relationshipMap)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// A, B and C agree on the first 4 tokens and disagree on the last 2. The automatic merge accepts the codes of A on the
+// first 4 tokens and declines the identical ones of B and C, which must still count when A is left out
+func TestGetLeaveOneAnnotatorOutKappasKeepsCodesOfAcceptedAnnotation(t *testing.T) {
+	var codeAlternatives []CodeAlternatives
+	for _, token := range []int{0, 1, 2, 3} {
+		for _, annotationName := range []string{"A", "B", "C"} {
+			codeAlternatives = append(codeAlternatives, makeTestCodeAlternative(len(codeAlternatives), annotationName, "Pending", "Task", fmt.Sprint("word", token), token))
+		}
+	}
+	codeAlternatives = append(codeAlternatives,
+		makeTestCodeAlternative(12, "A", "Pending", "Task", "y", 4),
+		makeTestCodeAlternative(13, "B", "Pending", "Goal", "y", 4),
+		makeTestCodeAlternative(14, "C", "Pending", "Software", "y", 4),
+		makeTestCodeAlternative(15, "A", "Pending", "Goal", "z", 5),
+		makeTestCodeAlternative(16, "B", "Pending", "Software", "z", 5),
+		makeTestCodeAlternative(17, "C", "Pending", "Task", "z", 5),
+	)
+	codeAlternatives = updateStatusOfCodeAlternatives(codeAlternatives, nil, 3, 2, getSpanMatching(spanMatchingExact, 0, ""), getMergePolicy("", "", ""))
+	if codeAlternatives[0].MergeStatus != "Accepted" || codeAlternatives[1].MergeStatus != "Declined" {
+		t.Fatalf("merge status = %s, %s, want Accepted, Declined", codeAlternatives[0].MergeStatus, codeAlternatives[1].MergeStatus)
+	}
+	var agreement = Agreement{
+		Annotations:      []string{"A", "B", "C"},
+		Tokens:           makeTestTokens(6),
+		CodeAlternatives: codeAlternatives,
+	}
+
+	var kappas = getKappas(agreement, testToreCategories, testToreRelationships)
+	var annotatorKappaDeltas = getLeaveOneAnnotatorOutKappas(agreement, testToreCategories, testToreRelationships, kappas)
+	if len(annotatorKappaDeltas) != 3 {
+		t.Fatalf("len(annotatorKappaDeltas) = %d, want 3", len(annotatorKappaDeltas))
+	}
+	// All annotations are symmetric, the remaining two agree on 4 of 6 tokens
+	for _, annotatorKappaDelta := range annotatorKappaDeltas {
+		var reducedAgreement = removeAnnotationFromAgreement(agreement, annotatorKappaDelta.AnnotationName)
+		if numberOfCodedTokens := len(getCodedTokenIndices(reducedAgreement)); numberOfCodedTokens != 6 {
+			t.Errorf("without %s, number of coded tokens = %d, want 6", annotatorKappaDelta.AnnotationName, numberOfCodedTokens)
+		}
+		testFloatsAreEqual(t, "fleissKappa without "+annotatorKappaDelta.AnnotationName, annotatorKappaDelta.Kappas[fleissKappaName], 19.0/31.0)
+	}
+}
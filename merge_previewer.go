@@ -0,0 +1,164 @@
+package main
+
+// Reasons for the merge status of a code alternative
+const (
+	mergeReasonAllAgreed           = "all_agreed"
+	mergeReasonMajorityAgreed      = "majority_agreed"
+	mergeReasonSpanDiffers         = "span_differs"
+	mergeReasonToreDiffers         = "tore_differs"
+	mergeReasonNameDiffers         = "name_differs"
+	mergeReasonRelationshipsDiffer = "relationships_differ"
+	mergeReasonMissingInAnnotation = "missing_in_annotation"
+)
+
+// MergeReason model, the annotation names are only set for mergeReasonMissingInAnnotation
+type MergeReason struct {
+	Reason          string   `json:"reason"`
+	AnnotationNames []string `json:"annotation_names,omitempty"`
+}
+
+// MergePreview model, the merge status a code alternative would get from the automatic merge and why
+type MergePreview struct {
+	Index          int           `json:"index"`
+	AnnotationName string        `json:"annotation_name"`
+	MergeStatus    string        `json:"merge_status"`
+	Reasons        []MergeReason `json:"reasons"`
+	MergeDetails   *MergeDetails `json:"merge_details,omitempty"`
+}
+
+// Runs the automatic merge on a copy of the code alternatives and relationships, and explains the status of every
+// code alternative
+// The merge is the majority merge, or Dawid-Skene with aggregationModeDawidSkene
+// Codes of an accepted span are compared with the accepted code, codes of an undecided span with each other
+func getMergePreviews(
+	codeAlternatives []CodeAlternatives,
+	toreRelationships []TORERelationship,
+	annotationNames []string,
+	requiredNumberOfAnnotations int,
+	spanMatching SpanMatching,
+	mergePolicy MergePolicy,
+	aggregationMode string,
+	confidenceThreshold float64,
+) []MergePreview {
+	var mergedCodeAlternatives = make([]CodeAlternatives, len(codeAlternatives))
+	copy(mergedCodeAlternatives, codeAlternatives)
+	// Merging relationships by majority points them to the accepted code, so their sources are copied as well
+	var mergedToreRelationships = copyToreRelationships(toreRelationships)
+	if aggregationMode == aggregationModeDawidSkene {
+		mergedCodeAlternatives, _ = updateStatusOfCodeAlternativesWithDawidSkene(mergedCodeAlternatives, mergedToreRelationships, annotationNames, confidenceThreshold, spanMatching, mergePolicy)
+	} else {
+		mergedCodeAlternatives = updateStatusOfCodeAlternatives(mergedCodeAlternatives, mergedToreRelationships, len(annotationNames), requiredNumberOfAnnotations, spanMatching, mergePolicy)
+	}
+
+	var relationshipMap = createRelationshipMapOfList(mergedToreRelationships)
+	var spanGroupsOfCodeAlternatives = getSpanGroupsOfCodeAlternatives(codeAlternatives, spanMatching)
+	var codeAlternativesOfSpanGroups = map[int][]int{}
+	for i, spanGroup := range spanGroupsOfCodeAlternatives {
+		codeAlternativesOfSpanGroups[spanGroup] = append(codeAlternativesOfSpanGroups[spanGroup], i)
+	}
+
+	var mergePreviews = []MergePreview{}
+	for i, codeAlternative := range codeAlternatives {
+		var codeAlternativesOfSpanGroup = codeAlternativesOfSpanGroups[spanGroupsOfCodeAlternatives[i]]
+		var missingAnnotationNames = getAnnotationNamesWithoutCode(codeAlternatives, codeAlternativesOfSpanGroup, annotationNames)
+		var acceptedIndex = -1
+		for _, index := range codeAlternativesOfSpanGroup {
+			if mergedCodeAlternatives[index].MergeStatus == "Accepted" {
+				acceptedIndex = index
+			}
+		}
+
+		var reasons = []MergeReason{}
+		if acceptedIndex == -1 {
+			for _, index := range codeAlternativesOfSpanGroup {
+				reasons = appendDifferenceReasons(reasons, codeAlternative.Code, codeAlternatives[index].Code, relationshipMap)
+			}
+		} else {
+			if i != acceptedIndex {
+				reasons = appendDifferenceReasons(reasons, codeAlternative.Code, mergedCodeAlternatives[acceptedIndex].Code, relationshipMap)
+			}
+			// The accepted code and the codes that were merged into the accepted code share its reason
+			if len(reasons) == 0 {
+				var isUnanimous = len(missingAnnotationNames) == 0
+				for _, index := range codeAlternativesOfSpanGroup {
+					if len(appendDifferenceReasons(nil, codeAlternatives[index].Code, mergedCodeAlternatives[acceptedIndex].Code, relationshipMap)) != 0 {
+						isUnanimous = false
+					}
+				}
+				if isUnanimous {
+					reasons = append(reasons, MergeReason{Reason: mergeReasonAllAgreed})
+				} else {
+					reasons = append(reasons, MergeReason{Reason: mergeReasonMajorityAgreed})
+				}
+			}
+		}
+		if len(missingAnnotationNames) != 0 {
+			reasons = append(reasons, MergeReason{Reason: mergeReasonMissingInAnnotation, AnnotationNames: missingAnnotationNames})
+		}
+
+		mergePreviews = append(mergePreviews, MergePreview{
+			Index:          codeAlternative.Index,
+			AnnotationName: codeAlternative.AnnotationName,
+			MergeStatus:    mergedCodeAlternatives[i].MergeStatus,
+			Reasons:        reasons,
+			MergeDetails:   mergedCodeAlternatives[i].MergeDetails,
+		})
+	}
+	return mergePreviews
+}
+
+// Adds a reason for every field in which the two codes differ, unless the reasons already contain it
+func appendDifferenceReasons(reasons []MergeReason, a Code, b Code, relationshipMap map[int]TORERelationship) []MergeReason {
+	var differences = []struct {
+		reason  string
+		differs bool
+	}{
+		{mergeReasonSpanDiffers, getSpanKey(a.Tokens) != getSpanKey(b.Tokens)},
+		{mergeReasonToreDiffers, a.Tore != b.Tore},
+		{mergeReasonNameDiffers, a.Name != b.Name},
+		{mergeReasonRelationshipsDiffer, getRelationshipsKey(a.RelationshipMemberships, relationshipMap) != getRelationshipsKey(b.RelationshipMemberships, relationshipMap)},
+	}
+	for _, difference := range differences {
+		if !difference.differs {
+			continue
+		}
+		var isKnown = false
+		for _, reason := range reasons {
+			if reason.Reason == difference.reason {
+				isKnown = true
+			}
+		}
+		if !isKnown {
+			reasons = append(reasons, MergeReason{Reason: difference.reason})
+		}
+	}
+	return reasons
+}
+
+// Returns the annotations without a code in the span group, in the order of annotationNames
+func getAnnotationNamesWithoutCode(codeAlternatives []CodeAlternatives, codeAlternativesOfSpanGroup []int, annotationNames []string) []string {
+	var annotationNameSet = map[string]bool{}
+	for _, index := range codeAlternativesOfSpanGroup {
+		annotationNameSet[codeAlternatives[index].AnnotationName] = true
+	}
+	var missingAnnotationNames []string
+	for _, annotationName := range annotationNames {
+		if !annotationNameSet[annotationName] {
+			missingAnnotationNames = append(missingAnnotationNames, annotationName)
+		}
+	}
+	return missingAnnotationNames
+}
+
+// Returns a copy of the relationships with their own sources
+func copyToreRelationships(toreRelationships []TORERelationship) []TORERelationship {
+	var copiedToreRelationships = make([]TORERelationship, len(toreRelationships))
+	copy(copiedToreRelationships, toreRelationships)
+	for i, toreRelationship := range toreRelationships {
+		if toreRelationship.TOREEntity != nil {
+			var toreEntity = *toreRelationship.TOREEntity
+			copiedToreRelationships[i].TOREEntity = &toreEntity
+		}
+	}
+	return copiedToreRelationships
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// The preview merges relationships by majority as the merge does, but the relationships and codes keep their sources
+func TestGetMergePreviewsDoesNotChangeRelationships(t *testing.T) {
+	var codeAlternatives = []CodeAlternatives{
+		makeTestCodeAlternative(0, "A", "Pending", "Task", "x", 0, 1),
+		makeTestCodeAlternative(1, "B", "Pending", "Task", "x", 0, 1),
+		makeTestCodeAlternative(2, "C", "Pending", "Task", "x", 0, 1),
+	}
+	var toreRelationships = []TORERelationship{
+		makeTestRelationship(0, 0, "relatesTo", 4),
+		makeTestRelationship(1, 1, "refersTo", 3),
+		makeTestRelationship(2, 2, "refersTo", 3),
+	}
+	for i := range codeAlternatives {
+		codeAlternatives[i].Code.RelationshipMemberships = []*int{getIntPointer(i)}
+	}
+	var mergePolicy = getMergePolicy(mergePolicyMustMatch, mergePolicyMustMatch, mergePolicyMajority)
+	getMergePreviews(codeAlternatives, toreRelationships, []string{"A", "B", "C"}, 2, getSpanMatching(spanMatchingExact, 0, ""), mergePolicy, "", 0)
+
+	for i, toreRelationship := range toreRelationships {
+		if *toreRelationship.TOREEntity != i {
+			t.Errorf("source of relationship %d = %d, want %d", i, *toreRelationship.TOREEntity, i)
+		}
+		var memberships = codeAlternatives[i].Code.RelationshipMemberships
+		if len(memberships) != 1 || *memberships[0] != i || codeAlternatives[i].MergeStatus != "Pending" {
+			t.Errorf("code %d = %s with relationships %v, want Pending with relationship %d", i, codeAlternatives[i].MergeStatus, memberships, i)
+		}
+	}
+}
+
+func TestGetMergePreviewsReasons(t *testing.T) {
+	var allAgreed = []MergeReason{{Reason: mergeReasonAllAgreed}}
+	var majorityAgreed = []MergeReason{{Reason: mergeReasonMajorityAgreed}}
+	// Only this code of C has a relationship
+	var toreRelationships = []TORERelationship{makeTestRelationship(0, 2, "refersTo", 3)}
+	var codeWithRelationship = makeTestCodeAlternative(2, "C", "Pending", "Task", "x", 0)
+	codeWithRelationship.Code.RelationshipMemberships = []*int{getIntPointer(0)}
+	var tests = []struct {
+		name                        string
+		codeAlternatives            []CodeAlternatives
+		requiredNumberOfAnnotations int
+		wantStatuses                []string
+		wantReasons                 [][]MergeReason
+	}{
+		{"all agreed", []CodeAlternatives{
+			makeTestCodeAlternative(0, "A", "Pending", "Task", "x", 0),
+			makeTestCodeAlternative(1, "B", "Pending", "Task", "x", 0),
+			makeTestCodeAlternative(2, "C", "Pending", "Task", "x", 0),
+		}, 3, []string{"Accepted", "Declined", "Declined"}, [][]MergeReason{allAgreed, allAgreed, allAgreed}},
+		{"tore differs", []CodeAlternatives{
+			makeTestCodeAlternative(0, "A", "Pending", "Task", "x", 0),
+			makeTestCodeAlternative(1, "B", "Pending", "Task", "x", 0),
+			makeTestCodeAlternative(2, "C", "Pending", "Goal", "x", 0),
+		}, 2, []string{"Accepted", "Declined", "Declined"}, [][]MergeReason{
+			majorityAgreed, majorityAgreed, {{Reason: mergeReasonToreDiffers}},
+		}},
+		{"name differs", []CodeAlternatives{
+			makeTestCodeAlternative(0, "A", "Pending", "Task", "x", 0),
+			makeTestCodeAlternative(1, "B", "Pending", "Task", "x", 0),
+			makeTestCodeAlternative(2, "C", "Pending", "Task", "y", 0),
+		}, 2, []string{"Accepted", "Declined", "Declined"}, [][]MergeReason{
+			majorityAgreed, majorityAgreed, {{Reason: mergeReasonNameDiffers}},
+		}},
+		{"relationships differ", []CodeAlternatives{
+			makeTestCodeAlternative(0, "A", "Pending", "Task", "x", 0),
+			makeTestCodeAlternative(1, "B", "Pending", "Task", "x", 0),
+			codeWithRelationship,
+		}, 2, []string{"Accepted", "Declined", "Declined"}, [][]MergeReason{
+			majorityAgreed, majorityAgreed, {{Reason: mergeReasonRelationshipsDiffer}},
+		}},
+		{"missing in annotation", []CodeAlternatives{
+			makeTestCodeAlternative(0, "A", "Pending", "Task", "x", 0),
+			makeTestCodeAlternative(1, "B", "Pending", "Task", "x", 0),
+		}, 2, []string{"Accepted", "Declined"}, [][]MergeReason{
+			{{Reason: mergeReasonMajorityAgreed}, {Reason: mergeReasonMissingInAnnotation, AnnotationNames: []string{"C"}}},
+			{{Reason: mergeReasonMajorityAgreed}, {Reason: mergeReasonMissingInAnnotation, AnnotationNames: []string{"C"}}},
+		}},
+		// Without an accepted code, the codes of the span are compared with each other
+		{"undecided", []CodeAlternatives{
+			makeTestCodeAlternative(0, "A", "Pending", "Task", "x", 0),
+			makeTestCodeAlternative(1, "B", "Pending", "Goal", "y", 0),
+		}, 2, []string{"Pending", "Pending"}, [][]MergeReason{
+			{{Reason: mergeReasonToreDiffers}, {Reason: mergeReasonNameDiffers}, {Reason: mergeReasonMissingInAnnotation, AnnotationNames: []string{"C"}}},
+			{{Reason: mergeReasonToreDiffers}, {Reason: mergeReasonNameDiffers}, {Reason: mergeReasonMissingInAnnotation, AnnotationNames: []string{"C"}}},
+		}},
+	}
+	for _, test := range tests {
+		var mergePolicy = getMergePolicy(mergePolicyMustMatch, mergePolicyMustMatch, mergePolicyMustMatch)
+		var mergePreviews = getMergePreviews(test.codeAlternatives, toreRelationships, []string{"A", "B", "C"}, test.requiredNumberOfAnnotations, getSpanMatching(spanMatchingExact, 0, ""), mergePolicy, "", 0)
+		for i, mergePreview := range mergePreviews {
+			if mergePreview.MergeStatus != test.wantStatuses[i] {
+				t.Errorf("%s: status of %d = %s, want %s", test.name, i, mergePreview.MergeStatus, test.wantStatuses[i])
+			}
+			if !reflect.DeepEqual(mergePreview.Reasons, test.wantReasons[i]) {
+				t.Errorf("%s: reasons of %d = %+v, want %+v", test.name, i, mergePreview.Reasons, test.wantReasons[i])
+			}
+		}
+	}
+}
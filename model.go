@@ -55,6 +55,13 @@ type AgreementStatistics struct {
 	CurrentKappa float64 `json:"current_kappa" bson:"current_kappa"`
 }
 
+// KappaSnapshot model, the kappas of an agreement after NumberOfDecisions code alternatives were accepted or declined
+type KappaSnapshot struct {
+	CreatedAt         time.Time          `json:"created_at" bson:"created_at"`
+	NumberOfDecisions int                `json:"number_of_decisions" bson:"number_of_decisions"`
+	Kappas            map[string]float64 `json:"kappas" bson:"kappas"`
+}
+
 // CodeAlternatives model, shows all code alternatives from all annotations, MergeStatus can be set to Pending, Accepted or Declined
 type CodeAlternatives struct {
 	AnnotationName string `json:"annotation_name" bson:"annotation_name"`
@@ -62,6 +69,24 @@ type CodeAlternatives struct {
 	Index          int    `json:"index" bson:"index"`
 
 	Code Code `json:"code" bson:"code"`
+
+	MergeDetails *MergeDetails `json:"merge_details,omitempty" bson:"merge_details,omitempty"`
+}
+
+// RelationshipAlternatives model, shows all relationships from all annotations, MergeStatus can be set to Pending, Accepted or Declined
+// Index is the index of the relationship in the TORERelationships of the agreement
+type RelationshipAlternatives struct {
+	AnnotationName string `json:"annotation_name" bson:"annotation_name"`
+	MergeStatus    string `validate:"nonzero" json:"merge_status" bson:"merge_status"`
+	Index          int    `json:"index" bson:"index"`
+}
+
+// MergeDetails model, how an accepted code was merged, if the merged codes only overlap or differ in some fields
+type MergeDetails struct {
+	SpanMatchingStrategy string   `json:"span_matching_strategy" bson:"span_matching_strategy"`
+	ChosenSpan           []int    `json:"chosen_span" bson:"chosen_span"`
+	OtherSpans           [][]int  `json:"other_spans" bson:"other_spans"`
+	FieldsToReview       []string `json:"fields_to_review" bson:"fields_to_review"`
 }
 
 // Agreement model
@@ -77,13 +102,54 @@ type Agreement struct {
 	Tokens            []Token            `json:"tokens" bson:"tokens"`
 	TORERelationships []TORERelationship `json:"tore_relationships" bson:"tore_relationships"`
 
-	CodeAlternatives    []CodeAlternatives    `json:"code_alternatives" bson:"code_alternatives"`
-	AgreementStatistics []AgreementStatistics `json:"agreement_statistics" bson:"agreement_statistics"`
+	CodeAlternatives         []CodeAlternatives         `json:"code_alternatives" bson:"code_alternatives"`
+	RelationshipAlternatives []RelationshipAlternatives `json:"relationship_alternatives" bson:"relationship_alternatives"`
+	AgreementStatistics      []AgreementStatistics      `json:"agreement_statistics" bson:"agreement_statistics"`
+	KappaHistory             []KappaSnapshot            `json:"kappa_history" bson:"kappa_history"`
 
 	IsCompleted bool `json:"is_completed" bson:"is_completed"`
 	SentenceTokenizationEnabledForAgreement bool `json:"sentence_tokenization_enabled_for_agreement" bson:"sentence_tokenization_enabled_for_agreement"`
 }
 
+// KappaRequest model, an agreement together with the options for the calculation of the kappas
+// The agreement is embedded, so the fields of the agreement stay on the top level of the body
+type KappaRequest struct {
+	Agreement
+
+	SpanIoUThreshold float64 `json:"span_iou_threshold"`
+
+	// Bootstrap confidence intervals are only calculated, if BootstrapIterations is greater than 0
+	// BootstrapUnit is either "token" or "document", requests with more than maxBootstrapIterations are rejected
+	BootstrapIterations      int     `json:"bootstrap_iterations"`
+	BootstrapSeed            int64   `json:"bootstrap_seed"`
+	BootstrapUnit            string  `json:"bootstrap_unit"`
+	BootstrapConfidenceLevel float64 `json:"bootstrap_confidence_level"`
+
+	// Weighted kappas are calculated with these distances, or the configured ones if there are none
+	ToreCategoryDistances ToreCategoryDistances `json:"tore_category_distances"`
+	// Tore categories are aggregated to levels with this mapping, or the configured one if there is none
+	ToreLevelMapping ToreLevelMapping `json:"tore_level_mapping"`
+
+	// If set, the kappas are recalculated with every annotation removed in turn
+	LeaveOneAnnotatorOut bool `json:"leave_one_annotator_out"`
+
+	// Number of lemmas in the stratified agreements, 20 if not set
+	StratumLemmaLimit int `json:"stratum_lemma_limit"`
+}
+
+// HotspotRequest model, an agreement together with the options for the disagreement hotspots
+type HotspotRequest struct {
+	Agreement
+
+	GroupSpans bool `json:"group_spans"`
+	Limit      int  `json:"limit"`
+}
+
+// IntraRaterRequest model, the pairs of annotations to compare
+type IntraRaterRequest struct {
+	AnnotationPairs []AnnotationPair `json:"annotation_pairs"`
+}
+
 // ResponseMessage model
 type ResponseMessage struct {
 	Message string `json:"message"`
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// CohenKappaMatrix model, Kappas[i][j] is the cohen kappa between AnnotationNames[i] and AnnotationNames[j]
+type CohenKappaMatrix struct {
+	AnnotationNames []string    `json:"annotation_names"`
+	Kappas          [][]float64 `json:"kappas"`
+}
+
+func getCohenKappaMatrix(
+	agreement Agreement,
+	toreCategories ToreCategories,
+	toreRelationships ToreRelationships,
+) CohenKappaMatrix {
+
+	var nameSet = map[string]bool{}
+	for _, alternative := range agreement.CodeAlternatives {
+		nameSet[alternative.Code.Name] = true
+	}
+	numberOfRels, numberOfCategories, _ := getNumberOfRelsCategoriesAndWordCodes(nameSet, toreRelationships, toreCategories)
+	relNameMap, categoryMap, wordCodeMap := createMaps(agreement, toreCategories, toreRelationships)
+	existingRelsMap := createExistingRelsMap(agreement)
+
+	tokenLabels := getTokenLabelsOfAnnotations(agreement, wordCodeMap, categoryMap, relNameMap, existingRelsMap, numberOfCategories, numberOfRels)
+
+	var numberOfAnnotations = len(agreement.Annotations)
+	var kappas = make([][]float64, numberOfAnnotations)
+	for i := 0; i < numberOfAnnotations; i++ {
+		kappas[i] = make([]float64, numberOfAnnotations)
+	}
+	for i := 0; i < numberOfAnnotations; i++ {
+		kappas[i][i] = 1.0
+		for j := i + 1; j < numberOfAnnotations; j++ {
+			var kappa = calculateCohenKappa(tokenLabels[agreement.Annotations[i]], tokenLabels[agreement.Annotations[j]])
+			// Special cases, when Kappa is either smaller than 0 or None because some denominator is 0
+			if (kappa < 0) || (kappa != kappa) {
+				kappa = 0.0
+			}
+			kappas[i][j] = kappa
+			kappas[j][i] = kappa
+		}
+	}
+	return CohenKappaMatrix{
+		AnnotationNames: agreement.Annotations,
+		Kappas:          kappas,
+	}
+}
+
+// Returns for every annotation one label per coded token, in the order of the agreement tokens
+// The label is made of all positions the codes of the annotation have for the token, "" if the annotation has no code
+func getTokenLabelsOfAnnotations(
+	agreement Agreement,
+	wordCodeMap map[string]int,
+	categoryMap map[string]int,
+	relNameMap map[string]int,
+	existingRelsMap map[int]string,
+	numberOfCategories int,
+	numberOfRels int,
+) map[string][]string {
+	var tokenMap = groupCodeAlternativesByToken(agreement.CodeAlternatives)
+	var tokenLabels = map[string][]string{}
+	for _, token := range agreement.Tokens {
+		var tokenIndex = *token.Index
+		if len(tokenMap[tokenIndex]) == 0 {
+			continue
+		}
+		var codesOfAnnotations = getCodesOfAnnotationsForToken(tokenMap[tokenIndex], agreement.Annotations)
+		for _, annotationName := range agreement.Annotations {
+			var label = ""
+			if len(codesOfAnnotations[annotationName]) != 0 {
+				var positions []int
+				for _, codeAlternative := range codesOfAnnotations[annotationName] {
+					positions = append(positions, getCodePositions(codeAlternative, wordCodeMap, categoryMap, relNameMap, existingRelsMap, numberOfCategories, numberOfRels)...)
+				}
+				sort.Ints(positions)
+				label = fmt.Sprint(positions)
+			}
+			tokenLabels[annotationName] = append(tokenLabels[annotationName], label)
+		}
+	}
+	return tokenLabels
+}
+
+// Cohen's kappa for two lists of labels of the same tokens
+func calculateCohenKappa(labelsA []string, labelsB []string) float64 {
+	var numberOfTokens = float64(len(labelsA))
+	var numberOfAgreements = float64(0)
+	var labelCountsA = map[string]float64{}
+	var labelCountsB = map[string]float64{}
+	for i := range labelsA {
+		if labelsA[i] == labelsB[i] {
+			numberOfAgreements++
+		}
+		labelCountsA[labelsA[i]]++
+		labelCountsB[labelsB[i]]++
+	}
+	var po = numberOfAgreements / numberOfTokens
+	var pe = float64(0)
+	for label, countA := range labelCountsA {
+		pe += (countA / numberOfTokens) * (labelCountsB[label] / numberOfTokens)
+	}
+	// This is only for the special case, when denominator is 0
+	if (1.0 - pe) == 0.0 {
+		return 1.0
+	}
+	return (po - pe) / (1.0 - pe)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// RelationshipAgreementOfPair model, arc level agreement between two annotations
+// Precision and recall are measured for the arcs of annotation A against the arcs of annotation B
+type RelationshipAgreementOfPair struct {
+	AnnotationNameA string  `json:"annotation_name_a"`
+	AnnotationNameB string  `json:"annotation_name_b"`
+	Precision       float64 `json:"precision"`
+	Recall          float64 `json:"recall"`
+	F1              float64 `json:"f1"`
+}
+
+// RelationshipAgreement model, the arc agreements of all pairs of annotations and the kappa of relationship names
+type RelationshipAgreement struct {
+	Pairs                 []RelationshipAgreementOfPair `json:"pairs"`
+	F1                    float64                       `json:"f1"`
+	NumberOfArcs          int                           `json:"number_of_arcs"`
+	RelationshipNameKappa float64                       `json:"relationship_name_kappa"`
+}
+
+// An arc connects the tokens of a code with target tokens through a relationship
+type relationshipArc struct {
+	sourceKey        string
+	targetKey        string
+	relationshipName string
+}
+
+func getRelationshipAgreement(agreement Agreement) RelationshipAgreement {
+	var arcsOfAnnotations = getArcsOfAnnotations(agreement)
+
+	var relationshipAgreement = RelationshipAgreement{Pairs: []RelationshipAgreementOfPair{}}
+	for i, annotationNameA := range agreement.Annotations {
+		for _, annotationNameB := range agreement.Annotations[i+1:] {
+			precision, recall, f1 := calculateArcPrecisionRecallAndF1(arcsOfAnnotations[annotationNameA], arcsOfAnnotations[annotationNameB])
+			relationshipAgreement.Pairs = append(relationshipAgreement.Pairs, RelationshipAgreementOfPair{
+				AnnotationNameA: annotationNameA,
+				AnnotationNameB: annotationNameB,
+				Precision:       precision,
+				Recall:          recall,
+				F1:              f1,
+			})
+			relationshipAgreement.F1 += f1
+		}
+	}
+	if len(relationshipAgreement.Pairs) != 0 {
+		relationshipAgreement.F1 /= float64(len(relationshipAgreement.Pairs))
+	}
+
+	var numberOfArcs, relationshipNameKappa = calculateRelationshipNameKappa(arcsOfAnnotations, agreement.Annotations)
+	relationshipAgreement.NumberOfArcs = numberOfArcs
+	relationshipAgreement.RelationshipNameKappa = relationshipNameKappa
+	return relationshipAgreement
+}
+
+// Returns the arcs of every annotation, taken from the relationship memberships of its codes
+func getArcsOfAnnotations(agreement Agreement) map[string][]relationshipArc {
+	var relationshipMap = createRelationshipMap(agreement)
+	var arcsOfAnnotations = map[string][]relationshipArc{}
+	for annotationName, codes := range getSpansOfAnnotations(agreement) {
+		arcsOfAnnotations[annotationName] = getArcsOfCodes(codes, relationshipMap)
+	}
+	return arcsOfAnnotations
+}
+
+func getArcsOfCodes(codes []Code, relationshipMap map[int]TORERelationship) []relationshipArc {
+	var arcs []relationshipArc
+	for _, code := range codes {
+		for _, memberIndex := range code.RelationshipMemberships {
+			toreRelationship, ok := relationshipMap[*memberIndex]
+			if !ok {
+				continue
+			}
+			arcs = append(arcs, relationshipArc{
+				sourceKey:        getSpanKey(code.Tokens),
+				targetKey:        getSpanKey(toreRelationship.TargetTokens),
+				relationshipName: toreRelationship.RelationshipName,
+			})
+		}
+	}
+	return arcs
+}
+
+// Maps the index of every relationship in the agreement to the relationship
+func createRelationshipMap(agreement Agreement) map[int]TORERelationship {
+	return createRelationshipMapOfList(agreement.TORERelationships)
+}
+
+func createRelationshipMapOfList(toreRelationships []TORERelationship) map[int]TORERelationship {
+	var relationshipMap = map[int]TORERelationship{}
+	for _, toreRelationship := range toreRelationships {
+		if toreRelationship.Index != nil {
+			relationshipMap[*toreRelationship.Index] = toreRelationship
+		}
+	}
+	return relationshipMap
+}
+
+// Every arc can only be matched once
+func calculateArcPrecisionRecallAndF1(arcsA []relationshipArc, arcsB []relationshipArc) (float64, float64, float64) {
+	// This is only for the special case, when both annotations have no arcs
+	if len(arcsA) == 0 && len(arcsB) == 0 {
+		return 1.0, 1.0, 1.0
+	}
+	var arcCountsA = map[relationshipArc]int{}
+	for _, arc := range arcsA {
+		arcCountsA[arc]++
+	}
+	var matches = 0
+	for _, arc := range arcsB {
+		if arcCountsA[arc] > 0 {
+			arcCountsA[arc]--
+			matches++
+		}
+	}
+	var precision, recall = 0.0, 0.0
+	if len(arcsA) != 0 {
+		precision = float64(matches) / float64(len(arcsA))
+	}
+	if len(arcsB) != 0 {
+		recall = float64(matches) / float64(len(arcsB))
+	}
+	return precision, recall, float64(2*matches) / float64(len(arcsA)+len(arcsB))
+}
+
+// Fleiss kappa over the relationship names. Every connection of source and target tokens drawn by any annotation
+// is a unit, annotations without an arc for this connection assign no relationship name
+// Returns the number of units and the kappa
+func calculateRelationshipNameKappa(arcsOfAnnotations map[string][]relationshipArc, annotationNames []string) (int, float64) {
+	var namesOfConnections = map[string]map[string][]string{}
+	var connectionKeys []string
+	for _, annotationName := range annotationNames {
+		for _, arc := range arcsOfAnnotations[annotationName] {
+			var connectionKey = arc.sourceKey + "->" + arc.targetKey
+			if _, ok := namesOfConnections[connectionKey]; !ok {
+				namesOfConnections[connectionKey] = map[string][]string{}
+				connectionKeys = append(connectionKeys, connectionKey)
+			}
+			namesOfConnections[connectionKey][annotationName] = append(namesOfConnections[connectionKey][annotationName], arc.relationshipName)
+		}
+	}
+
+	var labelMap = map[string]int{"": 0}
+	var rows [][]string
+	for _, connectionKey := range connectionKeys {
+		var row []string
+		for _, annotationName := range annotationNames {
+			var relationshipNames = namesOfConnections[connectionKey][annotationName]
+			sort.Strings(relationshipNames)
+			var label = strings.Join(relationshipNames, ",")
+			if _, ok := labelMap[label]; !ok {
+				labelMap[label] = len(labelMap)
+			}
+			row = append(row, label)
+		}
+		rows = append(rows, row)
+	}
+
+	var dataMatrix = make([]map[int]int, len(rows))
+	var sumOfAllCells = 0
+	for i, row := range rows {
+		dataMatrix[i] = map[int]int{}
+		for _, label := range row {
+			dataMatrix[i][labelMap[label]]++
+			sumOfAllCells++
+		}
+	}
+	kappa, _, _ := calculateKappas(dataMatrix, dataMatrix, float64(sumOfAllCells), len(dataMatrix))
+	// Special cases, when Kappa is either smaller than 0 or None because some denominator is 0
+	if (kappa < 0) || (kappa != kappa) {
+		kappa = 0.0
+	}
+	return len(rows), kappa
+}
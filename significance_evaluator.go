@@ -0,0 +1,63 @@
+package main
+
+import (
+	"math"
+)
+
+// FleissKappaSignificance model, test of fleiss kappa against the hypothesis of chance agreement
+// The kappa is not cut off at 0, PValue is one-sided for a kappa greater than 0
+type FleissKappaSignificance struct {
+	FleissKappa    float64 `json:"fleiss_kappa"`
+	StandardError  float64 `json:"standard_error"`
+	ZScore         float64 `json:"z_score"`
+	PValue         float64 `json:"p_value"`
+	Interpretation string  `json:"interpretation"`
+}
+
+// Tests fleiss kappa of the data matrices of getDataMatrices, so the matrices of the kappas are not built again
+func getFleissKappaSignificance(
+	dataMatrix []map[int]int,
+	dataMatrixForRowCalculation []map[int]int,
+) FleissKappaSignificance {
+	var sumOfAllCells = 0
+	for _, sumOfColumn := range getSumsOfColumns(dataMatrix) {
+		sumOfAllCells += sumOfColumn
+	}
+	fleissKappa, _, standardError := calculateKappas(dataMatrix, dataMatrixForRowCalculation, float64(sumOfAllCells), len(dataMatrix))
+
+	var significance = FleissKappaSignificance{
+		FleissKappa:    fleissKappa,
+		StandardError:  standardError,
+		Interpretation: getLandisKochInterpretation(fleissKappa),
+	}
+	// Without a standard error, e.g. because all tokens have the same code, no test is possible
+	if standardError > 0 && !math.IsInf(standardError, 0) && fleissKappa == fleissKappa {
+		significance.ZScore = fleissKappa / standardError
+		significance.PValue = 0.5 * math.Erfc(significance.ZScore/math.Sqrt2)
+	} else {
+		significance.StandardError = 0.0
+		significance.PValue = 1.0
+	}
+	if fleissKappa != fleissKappa {
+		significance.FleissKappa = 0.0
+	}
+	return significance
+}
+
+// Returns the band of Landis and Koch (1977) a kappa falls into
+func getLandisKochInterpretation(kappa float64) string {
+	switch {
+	case kappa != kappa || kappa < 0:
+		return "Poor"
+	case kappa <= 0.2:
+		return "Slight"
+	case kappa <= 0.4:
+		return "Fair"
+	case kappa <= 0.6:
+		return "Moderate"
+	case kappa <= 0.8:
+		return "Substantial"
+	default:
+		return "Almost perfect"
+	}
+}
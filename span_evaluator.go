@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+const defaultSpanIoUThreshold = 0.5
+
+// SpanAgreement model, span level F1 scores between two annotations
+type SpanAgreement struct {
+	AnnotationNameA string  `json:"annotation_name_a"`
+	AnnotationNameB string  `json:"annotation_name_b"`
+	ToreExactF1     float64 `json:"tore_exact_f1"`
+	ToreOverlapF1   float64 `json:"tore_overlap_f1"`
+	NameExactF1     float64 `json:"name_exact_f1"`
+	NameOverlapF1   float64 `json:"name_overlap_f1"`
+}
+
+// SpanAgreements model, the span agreements of all pairs of annotations and their means
+type SpanAgreements struct {
+	IoUThreshold  float64         `json:"iou_threshold"`
+	Pairs         []SpanAgreement `json:"pairs"`
+	ToreExactF1   float64         `json:"tore_exact_f1"`
+	ToreOverlapF1 float64         `json:"tore_overlap_f1"`
+	NameExactF1   float64         `json:"name_exact_f1"`
+	NameOverlapF1 float64         `json:"name_overlap_f1"`
+}
+
+func getSpanAgreements(agreement Agreement, iouThreshold float64) SpanAgreements {
+	if iouThreshold <= 0 {
+		iouThreshold = defaultSpanIoUThreshold
+	}
+	var spansOfAnnotations = getSpansOfAnnotations(agreement)
+	var getTore = func(code Code) string { return code.Tore }
+	var getName = func(code Code) string { return code.Name }
+
+	var spanAgreements = SpanAgreements{IoUThreshold: iouThreshold, Pairs: []SpanAgreement{}}
+	for i, annotationNameA := range agreement.Annotations {
+		for _, annotationNameB := range agreement.Annotations[i+1:] {
+			var spansA = spansOfAnnotations[annotationNameA]
+			var spansB = spansOfAnnotations[annotationNameB]
+			toreExactF1, toreOverlapF1 := calculateSpanF1(spansA, spansB, getTore, iouThreshold)
+			nameExactF1, nameOverlapF1 := calculateSpanF1(spansA, spansB, getName, iouThreshold)
+			spanAgreements.Pairs = append(spanAgreements.Pairs, SpanAgreement{
+				AnnotationNameA: annotationNameA,
+				AnnotationNameB: annotationNameB,
+				ToreExactF1:     toreExactF1,
+				ToreOverlapF1:   toreOverlapF1,
+				NameExactF1:     nameExactF1,
+				NameOverlapF1:   nameOverlapF1,
+			})
+		}
+	}
+
+	if len(spanAgreements.Pairs) != 0 {
+		for _, pair := range spanAgreements.Pairs {
+			spanAgreements.ToreExactF1 += pair.ToreExactF1
+			spanAgreements.ToreOverlapF1 += pair.ToreOverlapF1
+			spanAgreements.NameExactF1 += pair.NameExactF1
+			spanAgreements.NameOverlapF1 += pair.NameOverlapF1
+		}
+		var numberOfPairs = float64(len(spanAgreements.Pairs))
+		spanAgreements.ToreExactF1 /= numberOfPairs
+		spanAgreements.ToreOverlapF1 /= numberOfPairs
+		spanAgreements.NameExactF1 /= numberOfPairs
+		spanAgreements.NameOverlapF1 /= numberOfPairs
+	}
+	return spanAgreements
+}
+
+// Returns the codes of every annotation, declined codes are ignored
+// Accepted codes are added to all other annotations that have no own code on any of its tokens
+func getSpansOfAnnotations(agreement Agreement) map[string][]Code {
+	var spansOfAnnotations = map[string][]Code{}
+	var coveredTokensOfAnnotations = map[string]map[int]bool{}
+	var acceptedCodes []CodeAlternatives
+	for _, codeAlternative := range agreement.CodeAlternatives {
+		if codeAlternative.MergeStatus == "Declined" {
+			continue
+		}
+		spansOfAnnotations[codeAlternative.AnnotationName] = append(spansOfAnnotations[codeAlternative.AnnotationName], codeAlternative.Code)
+		if _, ok := coveredTokensOfAnnotations[codeAlternative.AnnotationName]; !ok {
+			coveredTokensOfAnnotations[codeAlternative.AnnotationName] = map[int]bool{}
+		}
+		for _, token := range codeAlternative.Code.Tokens {
+			coveredTokensOfAnnotations[codeAlternative.AnnotationName][*token] = true
+		}
+		if codeAlternative.MergeStatus == "Accepted" {
+			acceptedCodes = append(acceptedCodes, codeAlternative)
+		}
+	}
+	for _, acceptedCode := range acceptedCodes {
+		for _, annotationName := range agreement.Annotations {
+			if annotationName == acceptedCode.AnnotationName {
+				continue
+			}
+			var hasOwnCode = false
+			for _, token := range acceptedCode.Code.Tokens {
+				if coveredTokensOfAnnotations[annotationName][*token] {
+					hasOwnCode = true
+					break
+				}
+			}
+			if !hasOwnCode {
+				spansOfAnnotations[annotationName] = append(spansOfAnnotations[annotationName], acceptedCode.Code)
+			}
+		}
+	}
+	return spansOfAnnotations
+}
+
+// Returns the exact match F1 and the overlap F1 of two lists of spans, spans with an empty label are ignored
+// For the overlap F1, two spans with the same label match, if their IoU is at least iouThreshold
+func calculateSpanF1(
+	spansA []Code,
+	spansB []Code,
+	getLabel func(Code) string,
+	iouThreshold float64,
+) (float64, float64) {
+	var labeledSpansA = filterLabeledSpans(spansA, getLabel)
+	var labeledSpansB = filterLabeledSpans(spansB, getLabel)
+	var numberOfSpans = len(labeledSpansA) + len(labeledSpansB)
+	// This is only for the special case, when both annotations have no spans
+	if numberOfSpans == 0 {
+		return 1.0, 1.0
+	}
+
+	// Exact matches, every span can only be matched once
+	var keyCountsA = map[string]int{}
+	for _, span := range labeledSpansA {
+		keyCountsA[getSpanKey(span.Tokens)+getLabel(span)]++
+	}
+	var exactMatches = 0
+	for _, span := range labeledSpansB {
+		var key = getSpanKey(span.Tokens) + getLabel(span)
+		if keyCountsA[key] > 0 {
+			keyCountsA[key]--
+			exactMatches++
+		}
+	}
+
+	// Overlap matches, the pairs with the highest IoU are matched first
+	type spanPair struct {
+		a   int
+		b   int
+		iou float64
+	}
+	var pairs []spanPair
+	for a, spanA := range labeledSpansA {
+		for b, spanB := range labeledSpansB {
+			if getLabel(spanA) != getLabel(spanB) {
+				continue
+			}
+			var iou = calculateIoU(spanA.Tokens, spanB.Tokens)
+			if iou >= iouThreshold {
+				pairs = append(pairs, spanPair{a, b, iou})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].iou > pairs[j].iou
+	})
+	var matchedA = map[int]bool{}
+	var matchedB = map[int]bool{}
+	var overlapMatches = 0
+	for _, pair := range pairs {
+		if !matchedA[pair.a] && !matchedB[pair.b] {
+			matchedA[pair.a] = true
+			matchedB[pair.b] = true
+			overlapMatches++
+		}
+	}
+
+	return float64(2*exactMatches) / float64(numberOfSpans), float64(2*overlapMatches) / float64(numberOfSpans)
+}
+
+func filterLabeledSpans(spans []Code, getLabel func(Code) string) []Code {
+	var labeledSpans []Code
+	for _, span := range spans {
+		if getLabel(span) != "" {
+			labeledSpans = append(labeledSpans, span)
+		}
+	}
+	return labeledSpans
+}
+
+// Returns the intersection over union of two sets of tokens
+func calculateIoU(a, b []*int) float64 {
+	var tokensOfA = map[int]bool{}
+	for _, token := range a {
+		tokensOfA[*token] = true
+	}
+	var tokensOfB = map[int]bool{}
+	for _, token := range b {
+		tokensOfB[*token] = true
+	}
+	var intersection = 0
+	for token := range tokensOfB {
+		if tokensOfA[token] {
+			intersection++
+		}
+	}
+	var union = len(tokensOfA) + len(tokensOfB) - intersection
+	if union == 0 {
+		return 0.0
+	}
+	return float64(intersection) / float64(union)
+}
+
+// Returns a key for a list of tokens, that is independent of the order of the tokens
+func getSpanKey(tokens []*int) string {
+	var tokenIndices []int
+	for _, token := range tokens {
+		tokenIndices = append(tokenIndices, *token)
+	}
+	return getSpanKeyOfIndices(tokenIndices)
+}
+
+// Returns the key of getSpanKey for token indices, e.g. of the spans in merge details
+func getSpanKeyOfIndices(tokenIndices []int) string {
+	var sortedTokenIndices = append([]int(nil), tokenIndices...)
+	sort.Ints(sortedTokenIndices)
+	return fmt.Sprint(sortedTokenIndices)
+}
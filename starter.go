@@ -32,8 +32,14 @@ func makeRouter() *mux.Router {
 
 	// Init
 	router.HandleFunc("/hitec/agreement/annotationinfo/", getInfoFromAnnotations).Methods("POST")
+	router.HandleFunc("/hitec/agreement/mergepreview/", getMergePreviewOfAnnotations).Methods("POST")
 	router.HandleFunc("/hitec/agreement/annotationexport/", createAnnotationFromAgreement).Methods("POST")
 	router.HandleFunc("/hitec/agreement/calculateKappa/", calculateKappaFromAgreement).Methods("POST")
+	router.HandleFunc("/hitec/agreement/hotspots/", getDisagreementHotspotsOfAgreement).Methods("POST")
+	router.HandleFunc("/hitec/agreement/annotatorevaluation/", evaluateAnnotatorsOfAgreement).Methods("POST")
+	router.HandleFunc("/hitec/agreement/kappahistory/snapshot/", addKappaSnapshotToAgreement).Methods("POST")
+	router.HandleFunc("/hitec/agreement/kappahistory/", getKappaHistoryOfAgreement).Methods("POST")
+	router.HandleFunc("/hitec/agreement/intrarater/", evaluateIntraRaterReliability).Methods("POST")
 	return router
 }
 
@@ -55,31 +61,104 @@ func createKeyValuePairs(m map[string]interface{}) string {
 
 // calculateKappaFromAgreement make and return the kappas
 func calculateKappaFromAgreement(w http.ResponseWriter, r *http.Request) {
-	var agreement Agreement
-	err := json.NewDecoder(r.Body).Decode(&agreement)
+	var kappaRequest KappaRequest
+	err := json.NewDecoder(r.Body).Decode(&kappaRequest)
+	agreement := kappaRequest.Agreement
 	fmt.Printf("calculateKappaFromAgreement called: %s", agreement.Name)
 	if err != nil {
 		fmt.Printf("ERROR decoding body: %s, body: %v\n", err, r.Body)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	if kappaRequest.BootstrapIterations > maxBootstrapIterations {
+		fmt.Printf("ERROR %d bootstrap iterations are more than the maximum of %d\n", kappaRequest.BootstrapIterations, maxBootstrapIterations)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	err = testToreCategoryDistancesAreValid(kappaRequest.ToreCategoryDistances)
+	if err != nil {
+		fmt.Printf("ERROR invalid tore category distances: %s\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
 
 	// Get all possible categories and relationship types for calculation of kappas
 	toreCategories, err := RESTGetAllTores()
 	handleErrorWithResponse(w, err, "ERROR retrieving all tore categories")
 	toreRelationships, err := RESTGetAllRelationships()
 	handleErrorWithResponse(w, err, "ERROR retrieving all relationships")
+	toreCategoryDistances, err := getToreCategoryDistances(kappaRequest.ToreCategoryDistances)
+	if err != nil {
+		fmt.Printf("ERROR reading tore category distances: %s\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	toreLevelMapping, err := getToreLevelMapping(kappaRequest.ToreLevelMapping)
+	if err != nil {
+		fmt.Printf("ERROR reading tore level mapping: %s\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
 
-	// Get and parse kappas
-	fleissKappa, brennanKappa := getKappas(agreement, toreCategories, toreRelationships)
-	fmt.Printf("fleiss kappa is %v, brenan kappa is %v\n", fleissKappa, brennanKappa)
-	var body = map[string]float64{}
-	body["fleissKappa"] = fleissKappa
-	body["brennanKappa"] = brennanKappa
+	// Get and parse kappas, the data matrices are built once for the kappas and the significance of fleiss kappa
+	dataMatrix, dataMatrixForRowCalculation, reliabilityDataMatrix := getDataMatrices(agreement, toreCategories, toreRelationships)
+	kappas := calculateKappasFromDataMatrices(dataMatrix, dataMatrixForRowCalculation, reliabilityDataMatrix)
+	fmt.Printf("kappas are %v\n", kappas)
+	var body = map[string]interface{}{}
+	for kappaName, kappa := range kappas {
+		body[kappaName] = kappa
+	}
+	body["fleissKappaSignificance"] = getFleissKappaSignificance(dataMatrix, dataMatrixForRowCalculation)
+	body["cohenKappaMatrix"] = getCohenKappaMatrix(agreement, toreCategories, toreRelationships)
+	body["categoryAgreements"] = getCategoryAgreements(agreement, toreCategories)
+	body["documentAgreements"] = getDocumentAgreements(agreement, toreCategories, toreRelationships)
+	body["spanAgreements"] = getSpanAgreements(agreement, kappaRequest.SpanIoUThreshold)
+	body["relationshipAgreement"] = getRelationshipAgreement(agreement)
+	body["stratifiedAgreements"] = getStratifiedAgreements(agreement, toreCategories, toreRelationships, kappaRequest.StratumLemmaLimit)
+	if len(toreCategoryDistances) != 0 {
+		body["weightedKappas"] = getWeightedKappas(agreement, toreCategories, toreCategoryDistances)
+	}
+	body["aggregatedKappas"] = map[string]map[string]float64{
+		aggregationLevelCategory:  getAggregatedKappas(agreement, aggregationLevelCategory, toreLevelMapping),
+		aggregationLevelToreLevel: getAggregatedKappas(agreement, aggregationLevelToreLevel, toreLevelMapping),
+	}
+	if kappaRequest.LeaveOneAnnotatorOut {
+		body["leaveOneAnnotatorOut"] = getLeaveOneAnnotatorOutKappas(agreement, toreCategories, toreRelationships, kappas)
+	}
+	if kappaRequest.BootstrapIterations > 0 {
+		body["bootstrapConfidenceIntervals"] = getBootstrapConfidenceIntervals(agreement, toreCategories, toreRelationships, kappaRequest.BootstrapIterations, kappaRequest.BootstrapSeed, kappaRequest.BootstrapUnit, kappaRequest.BootstrapConfidenceLevel)
+	}
 
 	responseBody, err := json.Marshal(body)
 	if err != nil {
-		fmt.Printf("Failed to marshal fleiss and brennan kappa")
+		fmt.Printf("Failed to marshal kappas")
+	}
+	w.Write(responseBody)
+}
+
+// getDisagreementHotspotsOfAgreement make and return the tokens with the lowest agreement
+func getDisagreementHotspotsOfAgreement(w http.ResponseWriter, r *http.Request) {
+	var hotspotRequest HotspotRequest
+	err := json.NewDecoder(r.Body).Decode(&hotspotRequest)
+	agreement := hotspotRequest.Agreement
+	fmt.Printf("getDisagreementHotspotsOfAgreement called: %s", agreement.Name)
+	if err != nil {
+		fmt.Printf("ERROR decoding body: %s, body: %v\n", err, r.Body)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Get all possible categories and relationship types for calculation of the token agreements
+	toreCategories, err := RESTGetAllTores()
+	handleErrorWithResponse(w, err, "ERROR retrieving all tore categories")
+	toreRelationships, err := RESTGetAllRelationships()
+	handleErrorWithResponse(w, err, "ERROR retrieving all relationships")
+
+	hotspots := getDisagreementHotspots(agreement, toreCategories, toreRelationships, hotspotRequest.GroupSpans, hotspotRequest.Limit)
+
+	responseBody, err := json.Marshal(hotspots)
+	if err != nil {
+		fmt.Printf("Failed to marshal disagreement hotspots")
 	}
 	w.Write(responseBody)
 }
@@ -110,12 +189,23 @@ func getInfoFromAnnotations(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	relationshipAlternatives := initializeRelationshipAlternatives(toreRelationships, codeAlternatives)
+	var labelProposals []LabelProposal
+
 	completeConcurrences := body["completeConcurrences"].(bool)
 	fmt.Printf("CompleteConcurrences is set to %t", completeConcurrences)
 
 	if completeConcurrences {
-		fmt.Printf("\nAutomatically merge concurrent annotations\n")
-		codeAlternatives = updateStatusOfCodeAlternatives(codeAlternatives, toreRelationships, len(annotationNames))
+		requiredNumberOfAnnotations, spanMatching, mergePolicy := getMergeOptionsFromBody(body, len(annotationNames))
+		fmt.Printf("\nAutomatically merge concurrent annotations, %d of %d annotations have to agree on %s spans\n", requiredNumberOfAnnotations, len(annotationNames), spanMatching.Strategy)
+		aggregationMode, confidenceThreshold := getAggregationOptionsFromBody(body)
+		if aggregationMode == aggregationModeDawidSkene {
+			fmt.Printf("Aggregate codes with Dawid-Skene, proposals need a confidence of %f\n", confidenceThreshold)
+			codeAlternatives, labelProposals = updateStatusOfCodeAlternativesWithDawidSkene(codeAlternatives, toreRelationships, annotationNames, confidenceThreshold, spanMatching, mergePolicy)
+		} else {
+			codeAlternatives = updateStatusOfCodeAlternatives(codeAlternatives, toreRelationships, len(annotationNames), requiredNumberOfAnnotations, spanMatching, mergePolicy)
+		}
+		relationshipAlternatives = updateStatusOfRelationshipAlternatives(relationshipAlternatives, toreRelationships, codeAlternatives, len(annotationNames), requiredNumberOfAnnotations, spanMatching)
 	}
 
 	// parse the relevant fields into a struct
@@ -125,6 +215,8 @@ func getInfoFromAnnotations(w http.ResponseWriter, r *http.Request) {
 	relevantAgreementFields.Tokens = tokens
 	relevantAgreementFields.TORERelationships = toreRelationships
 	relevantAgreementFields.CodeAlternatives = codeAlternatives
+	relevantAgreementFields.RelationshipAlternatives = relationshipAlternatives
+	relevantAgreementFields.LabelProposals = labelProposals
 
 	finalRelevantFields, err := json.Marshal(relevantAgreementFields)
 	if err != nil {
@@ -133,6 +225,73 @@ func getInfoFromAnnotations(w http.ResponseWriter, r *http.Request) {
 	w.Write(finalRelevantFields)
 }
 
+// getMergeOptionsFromBody reads the optional options of the automatic merge, the defaults merge only unanimous codes
+func getMergeOptionsFromBody(body map[string]interface{}, numberOfAnnotations int) (int, SpanMatching, MergePolicy) {
+	// The optional majority threshold is either a number of annotations or a percentage
+	majorityThreshold, _ := body["majorityThreshold"].(float64)
+	majorityPercentage, _ := body["majorityPercentage"].(float64)
+	requiredNumberOfAnnotations := getRequiredNumberOfAnnotations(majorityThreshold, majorityPercentage, numberOfAnnotations)
+	// The optional span matching is "exact", "iou" or "head", the head token of a span is its "last" or "first" token
+	spanMatchingStrategy, _ := body["spanMatching"].(string)
+	spanIoUThreshold, _ := body["spanIoUThreshold"].(float64)
+	spanHeadToken, _ := body["spanHeadToken"].(string)
+	spanMatching := getSpanMatching(spanMatchingStrategy, spanIoUThreshold, spanHeadToken)
+	// The optional merge policy of tore, name and relationships is "must_match", "ignore" or "majority"
+	bodyMergePolicy, _ := body["mergePolicy"].(map[string]interface{})
+	torePolicy, _ := bodyMergePolicy["tore"].(string)
+	namePolicy, _ := bodyMergePolicy["name"].(string)
+	relationshipsPolicy, _ := bodyMergePolicy["relationships"].(string)
+	mergePolicy := getMergePolicy(torePolicy, namePolicy, relationshipsPolicy)
+	return requiredNumberOfAnnotations, spanMatching, mergePolicy
+}
+
+// getAggregationOptionsFromBody reads the optional aggregation mode, "majority" or "dawid_skene", and the confidence
+// threshold of Dawid-Skene
+func getAggregationOptionsFromBody(body map[string]interface{}) (string, float64) {
+	aggregationMode, _ := body["aggregationMode"].(string)
+	confidenceThreshold, ok := body["confidenceThreshold"].(float64)
+	if !ok {
+		confidenceThreshold = defaultDawidSkeneConfidenceThreshold
+	}
+	return aggregationMode, confidenceThreshold
+}
+
+// getMergePreviewOfAnnotations runs the automatic merge on the annotations without changing them
+// and returns the merge status every code alternative would get, with the reasons for it
+func getMergePreviewOfAnnotations(w http.ResponseWriter, r *http.Request) {
+	var body map[string]interface{}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	fmt.Printf("getMergePreviewOfAnnotations called: %s", createKeyValuePairs(body))
+	if err != nil {
+		fmt.Printf("ERROR decoding body: %s, body: %v\n", err, r.Body)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var annotationNames []string
+	bodyAnnotationNames, _ := body["annotationNames"].([]interface{})
+	for _, value := range bodyAnnotationNames {
+		annotationNames = append(annotationNames, value.(string))
+	}
+
+	_, _, toreRelationships, codeAlternatives, err := initializeInfoFromAnnotations(w, annotationNames)
+	if err != nil {
+		fmt.Printf("Error getting annotations, returning")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	requiredNumberOfAnnotations, spanMatching, mergePolicy := getMergeOptionsFromBody(body, len(annotationNames))
+	aggregationMode, confidenceThreshold := getAggregationOptionsFromBody(body)
+	mergePreviews := getMergePreviews(codeAlternatives, toreRelationships, annotationNames, requiredNumberOfAnnotations, spanMatching, mergePolicy, aggregationMode, confidenceThreshold)
+
+	responseBody, err := json.Marshal(mergePreviews)
+	if err != nil {
+		fmt.Printf("Failed to marshal merge previews")
+	}
+	w.Write(responseBody)
+}
+
 // createAnnotationFromAgreement create a new annotation from an agreement
 func createAnnotationFromAgreement(w http.ResponseWriter, r *http.Request) {
 	hasError := false
@@ -175,3 +334,149 @@ func createAnnotationFromAgreement(w http.ResponseWriter, r *http.Request) {
 	}
 	return
 }
+
+// evaluateAnnotatorsOfAgreement compare every annotation of a completed agreement with the accepted codes
+func evaluateAnnotatorsOfAgreement(w http.ResponseWriter, r *http.Request) {
+	var body map[string]interface{}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	fmt.Printf("evaluateAnnotatorsOfAgreement called: %s", createKeyValuePairs(body))
+	if err != nil {
+		fmt.Printf("ERROR decoding body: %s, body: %v\n", err, r.Body)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	agreementName := body["agreementName"].(string)
+
+	agreement, err := RESTGetAgreement(agreementName)
+	handleErrorWithResponse(w, err, "ERROR retrieving agreement")
+
+	// The accepted codes are only final, if the agreement is completed
+	if agreement.IsCompleted == false {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(ResponseMessage{Status: true, Message: "Failure: Agreement is not completed."})
+		return
+	}
+
+	toreCategories, err := RESTGetAllTores()
+	handleErrorWithResponse(w, err, "ERROR retrieving all tore categories")
+
+	annotatorQualities := getAnnotatorQualities(agreement, toreCategories)
+
+	responseBody, err := json.Marshal(annotatorQualities)
+	if err != nil {
+		fmt.Printf("Failed to marshal annotator qualities")
+	}
+	w.Write(responseBody)
+}
+
+// addKappaSnapshotToAgreement make and return the kappa history and statistics including the current kappas
+// The agreement is not stored, the returned fields have to be saved with the agreement
+func addKappaSnapshotToAgreement(w http.ResponseWriter, r *http.Request) {
+	var agreement Agreement
+	err := json.NewDecoder(r.Body).Decode(&agreement)
+	fmt.Printf("addKappaSnapshotToAgreement called: %s", agreement.Name)
+	if err != nil {
+		fmt.Printf("ERROR decoding body: %s, body: %v\n", err, r.Body)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Get all possible categories and relationship types for calculation of kappas
+	toreCategories, err := RESTGetAllTores()
+	handleErrorWithResponse(w, err, "ERROR retrieving all tore categories")
+	toreRelationships, err := RESTGetAllRelationships()
+	handleErrorWithResponse(w, err, "ERROR retrieving all relationships")
+
+	kappaHistoryFields := updateKappaHistory(agreement, toreCategories, toreRelationships)
+
+	responseBody, err := json.Marshal(kappaHistoryFields)
+	if err != nil {
+		fmt.Printf("Failed to marshal kappa history")
+	}
+	w.Write(responseBody)
+}
+
+// getKappaHistoryOfAgreement return the kappa history of a stored agreement as series
+func getKappaHistoryOfAgreement(w http.ResponseWriter, r *http.Request) {
+	var body map[string]interface{}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	fmt.Printf("getKappaHistoryOfAgreement called: %s", createKeyValuePairs(body))
+	if err != nil {
+		fmt.Printf("ERROR decoding body: %s, body: %v\n", err, r.Body)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	agreementName := body["agreementName"].(string)
+
+	agreement, err := RESTGetAgreement(agreementName)
+	handleErrorWithResponse(w, err, "ERROR retrieving agreement")
+
+	responseBody, err := json.Marshal(getKappaSeries(agreement.KappaHistory))
+	if err != nil {
+		fmt.Printf("Failed to marshal kappa series")
+	}
+	w.Write(responseBody)
+}
+
+// evaluateIntraRaterReliability make and return the agreement of every annotator with themselves between two rounds
+func evaluateIntraRaterReliability(w http.ResponseWriter, r *http.Request) {
+	var intraRaterRequest IntraRaterRequest
+	err := json.NewDecoder(r.Body).Decode(&intraRaterRequest)
+	fmt.Printf("evaluateIntraRaterReliability called: %v", intraRaterRequest.AnnotationPairs)
+	if err != nil {
+		fmt.Printf("ERROR decoding body: %s, body: %v\n", err, r.Body)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	for _, annotationPair := range intraRaterRequest.AnnotationPairs {
+		if annotationPair.FirstAnnotationName == annotationPair.SecondAnnotationName {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(ResponseMessage{Status: true, Message: "Failure: An annotation can not be compared with itself."})
+			return
+		}
+	}
+
+	// Get all possible categories and relationship types for calculation of kappas
+	toreCategories, err := RESTGetAllTores()
+	handleErrorWithResponse(w, err, "ERROR retrieving all tore categories")
+	toreRelationships, err := RESTGetAllRelationships()
+	handleErrorWithResponse(w, err, "ERROR retrieving all relationships")
+
+	// All pairs are checked before any agreement is calculated
+	var annotationsOfPairs [][]Annotation
+	for _, annotationPair := range intraRaterRequest.AnnotationPairs {
+		annotations, err := getAnnotationsByName(w, []string{annotationPair.FirstAnnotationName, annotationPair.SecondAnnotationName})
+		if err != nil {
+			fmt.Printf("Error getting annotations, returning")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if annotations[0].Dataset != annotations[1].Dataset {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(ResponseMessage{Status: true, Message: "Failure: Both annotations of a pair must be of the same dataset."})
+			return
+		}
+		if !testAnnotationsHaveSameTokens(annotations[0], annotations[1]) {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(ResponseMessage{Status: true, Message: "Failure: Both annotations of a pair must have the same tokens."})
+			return
+		}
+		annotationsOfPairs = append(annotationsOfPairs, annotations)
+	}
+
+	var intraRaterAgreements = []IntraRaterAgreement{}
+	for i, annotationPair := range intraRaterRequest.AnnotationPairs {
+		intraRaterAgreements = append(intraRaterAgreements, getIntraRaterAgreement(annotationPair, annotationsOfPairs[i], toreCategories, toreRelationships))
+	}
+
+	responseBody, err := json.Marshal(IntraRaterReliability{
+		Pairs:      intraRaterAgreements,
+		Annotators: getAnnotatorIntraRaterAgreements(intraRaterAgreements),
+	})
+	if err != nil {
+		fmt.Printf("Failed to marshal intra-rater agreements")
+	}
+	w.Write(responseBody)
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Requests with too many bootstrap iterations are rejected before any kappa is calculated
+func TestCalculateKappaFromAgreementRejectsTooManyBootstrapIterations(t *testing.T) {
+	var request = httptest.NewRequest("POST", "/hitec/agreement/calculateKappa/", strings.NewReader(`{"name": "agreement", "bootstrap_iterations": 10000000}`))
+	var recorder = httptest.NewRecorder()
+	calculateKappaFromAgreement(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+}
+
+func TestCalculateKappaFromAgreementRejectsInvalidDistances(t *testing.T) {
+	var request = httptest.NewRequest("POST", "/hitec/agreement/calculateKappa/", strings.NewReader(`{"name": "agreement", "tore_category_distances": {"Task": {"Goal": -1}}}`))
+	var recorder = httptest.NewRecorder()
+	calculateKappaFromAgreement(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+}
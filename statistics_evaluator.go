@@ -1,5 +1,10 @@
 package main
 
+import (
+	"math"
+	"sort"
+)
+
 type ToreCategories struct {
 	Tores []string `json:"tores" bson:"tores"`
 }
@@ -8,11 +13,33 @@ type ToreRelationships struct {
 	RelationshipNames []string `json:"relationship_names" bson:"relationship_names"`
 }
 
+// Names of the kappas, used as keys in the kappa response and as KappaName of the AgreementStatistics
+const (
+	fleissKappaName       = "fleissKappa"
+	brennanKappaName      = "brennanKappa"
+	krippendorffAlphaName = "krippendorffAlpha"
+	gwetAC1Name           = "gwetAC1"
+	scottPiName           = "scottPi"
+)
+
+// Returns all kappas of the agreement by their name
 func getKappas(
 	agreement Agreement,
 	toreCategories ToreCategories,
 	toreRelationships ToreRelationships,
-) (float64, float64) {
+) map[string]float64 {
+	dataMatrix, dataMatrixForRowCalculation, reliabilityDataMatrix := getDataMatrices(agreement, toreCategories, toreRelationships)
+	return calculateKappasFromDataMatrices(dataMatrix, dataMatrixForRowCalculation, reliabilityDataMatrix)
+}
+
+// Returns the dataMatrix, the dataMatrixForRowCalculation and the reliabilityDataMatrix
+// The rows belong to the coded tokens, in the order of agreement.Tokens. Every row is sparse and only
+// maps the positions of the codes that occur for the token to their count
+func getDataMatrices(
+	agreement Agreement,
+	toreCategories ToreCategories,
+	toreRelationships ToreRelationships,
+) ([]map[int]int, []map[int]int, []map[int]int) {
 
 	// Get and count all wordCodes
 	var nameSet = map[string]bool{}
@@ -21,68 +48,82 @@ func getKappas(
 		nameSet[alternative.Code.Name] = true
 		annotationSet[alternative.AnnotationName] = true
 	}
+	// Annotations without any code still count as annotations that assigned no code
+	for _, annotationName := range agreement.Annotations {
+		annotationSet[annotationName] = true
+	}
 
 	// This number can change, because categories, wordCodes and relationships can be added and removed
-	numberOfRels, numberOfCategories, numberOfWordCodes := getNumberOfRelsCategoriesAndWordCodes(nameSet, toreRelationships, toreCategories)
-
-	// The number of different codes possible for a token
-	var numberOfCodePossibilities = numberOfRels * numberOfCategories * numberOfWordCodes
+	numberOfRels, numberOfCategories, _ := getNumberOfRelsCategoriesAndWordCodes(nameSet, toreRelationships, toreCategories)
 
 	// Get and encode relationships in agreement
-	var existingRelsMap = map[int]string{}
-	for _, existingToreRel := range agreement.TORERelationships {
-		if existingToreRel.Index != nil {
-			existingRelsMap[*existingToreRel.Index] = existingToreRel.RelationshipName
-		}
-	}
+	existingRelsMap := createExistingRelsMap(agreement)
 
 	// Maps to look up position of code in dataMatrix
 	relNameMap, categoryMap, wordCodeMap := createMaps(agreement, toreCategories, toreRelationships)
 
 	// Datamatrix containing codes for all tokens
-	dataMatrix, dataMatrixForRowCalculation, sumOfAllCells, numberOfAssignedTokens := fillDataMatrices(agreement.CodeAlternatives, agreement, wordCodeMap, categoryMap, relNameMap, existingRelsMap, numberOfCategories, numberOfRels, len(annotationSet), numberOfCodePossibilities)
+	dataMatrix, dataMatrixForRowCalculation, reliabilityDataMatrix, _, _ := fillDataMatrices(agreement.CodeAlternatives, agreement, wordCodeMap, categoryMap, relNameMap, existingRelsMap, numberOfCategories, numberOfRels, len(annotationSet))
+	return dataMatrix, dataMatrixForRowCalculation, reliabilityDataMatrix
+}
+
+// Returns all kappas by their name. The rows of all matrices have to belong to the same tokens
+func calculateKappasFromDataMatrices(
+	dataMatrix []map[int]int,
+	dataMatrixForRowCalculation []map[int]int,
+	reliabilityDataMatrix []map[int]int,
+) map[string]float64 {
+	var sumOfAllCells = 0
+	for i, _ := range dataMatrix {
+		for _, count := range dataMatrix[i] {
+			sumOfAllCells += count
+		}
+	}
+
 	// Calculation of fleiss and brennan in the same method, because separating them would be more expensive
-	fleissKappa, brennanKappa := calculateKappas(numberOfCodePossibilities, dataMatrix, dataMatrixForRowCalculation, sumOfAllCells, numberOfAssignedTokens)
+	fleissKappa, brennanKappa, _ := calculateKappas(dataMatrix, dataMatrixForRowCalculation, float64(sumOfAllCells), len(dataMatrix))
+	krippendorffAlpha := calculateKrippendorffAlpha(reliabilityDataMatrix)
+	gwetAC1, scottPi := calculateGwetAC1AndScottPi(dataMatrixForRowCalculation)
 
-	// Special cases, when Kappas are either smaller than 0 or None because some denominator is 0
-	if (fleissKappa < 0) || (fleissKappa != fleissKappa) {
-		fleissKappa = 0.0
+	var kappas = map[string]float64{
+		fleissKappaName:       fleissKappa,
+		brennanKappaName:      brennanKappa,
+		krippendorffAlphaName: krippendorffAlpha,
+		gwetAC1Name:           gwetAC1,
+		scottPiName:           scottPi,
 	}
-	if (brennanKappa < 0) || (brennanKappa != brennanKappa) {
-		brennanKappa = 0.0
+	// Special cases, when Kappas are either smaller than 0 or None because some denominator is 0
+	for kappaName, kappa := range kappas {
+		if (kappa < 0) || (kappa != kappa) {
+			kappas[kappaName] = 0.0
+		}
 	}
-	return fleissKappa, brennanKappa
+	return kappas
 }
 
+// Returns fleiss kappa, brennan kappa and the standard error of fleiss kappa under the hypothesis of chance agreement
 func calculateKappas(
-	numberOfAlternatives int,
-	dataMatrix [][]int,
-	dataMatrixForRowCalculation [][]int,
+	dataMatrix []map[int]int,
+	dataMatrixForRowCalculation []map[int]int,
 	sumOfAllCells float64,
 	numberOfTokens int,
-) (float64, float64) {
+) (float64, float64, float64) {
 	// Calculate Fleiss Kappa
 
-	var pj = make([]float64, numberOfAlternatives)
+	var sumsOfColumns = getSumsOfColumns(dataMatrix)
 	var pc = float64(0)
-	for j := 0; j < numberOfAlternatives; j++ {
-		var sumOfColumn = 0
-		for i, _ := range dataMatrix {
-			sumOfColumn += dataMatrix[i][j]
-		}
-		pj[j] = float64(sumOfColumn) / sumOfAllCells
-		pc += pj[j] * pj[j]
+	var sumOfPjQj = float64(0)
+	var sumOfPjQjTimesQjMinusPj = float64(0)
+	for _, j := range getSortedPositions(sumsOfColumns) {
+		var pj = float64(sumsOfColumns[j]) / sumOfAllCells
+		pc += pj * pj
+		sumOfPjQj += pj * (1.0 - pj)
+		sumOfPjQjTimesQjMinusPj += pj * (1.0 - pj) * (1.0 - 2.0*pj)
 	}
 	var pi = make([]float64, numberOfTokens)
 	var sumOfPi = float64(0)
 	for i := 0; i < numberOfTokens; i++ {
-		var sumOfCodesInRow = float64(0)
-		var addedSquaresOfRow = float64(0)
-		for j := 0; j < numberOfAlternatives; j++ {
-			sumOfCodesInRow += float64(dataMatrixForRowCalculation[i][j])
-			addedSquaresOfRow += float64(dataMatrixForRowCalculation[i][j] * dataMatrixForRowCalculation[i][j])
-		}
-		pi[i] = (addedSquaresOfRow - sumOfCodesInRow) / (sumOfCodesInRow * (sumOfCodesInRow - 1))
+		pi[i] = calculateTokenAgreement(dataMatrixForRowCalculation[i])
 		sumOfPi += pi[i]
 	}
 	var pHead = sumOfPi / float64(numberOfTokens)
@@ -95,10 +136,148 @@ func calculateKappas(
 		fleissKappa = (pHead - pc) / (1.0 - pc)
 	}
 
+	// Standard error after Fleiss, Nee and Landis, the number of codes per token is its mean over all tokens
+	var codesPerToken = sumOfAllCells / float64(numberOfTokens)
+	var fleissStandardError = math.Sqrt(2.0) / (sumOfPjQj * math.Sqrt(float64(numberOfTokens)*codesPerToken*(codesPerToken-1.0))) *
+		math.Sqrt(sumOfPjQj*sumOfPjQj-sumOfPjQjTimesQjMinusPj)
+
 	// Calculate Brennan and Prediger Kappa
 	var brennanPc = sumOfAllCells / ((sumOfAllCells + 1) * (sumOfAllCells + 1))
 	var brennanKappa = (pHead - brennanPc) / (1 - brennanPc)
-	return fleissKappa, brennanKappa
+	return fleissKappa, brennanKappa, fleissStandardError
+}
+
+// Returns the share of agreeing pairs of codes of a token
+func calculateTokenAgreement(row map[int]int) float64 {
+	var sumOfCodesInRow = float64(0)
+	var addedSquaresOfRow = float64(0)
+	for _, count := range row {
+		sumOfCodesInRow += float64(count)
+		addedSquaresOfRow += float64(count * count)
+	}
+	return (addedSquaresOfRow - sumOfCodesInRow) / (sumOfCodesInRow * (sumOfCodesInRow - 1))
+}
+
+// Krippendorff's alpha for nominal data. Every token is a unit with its own number of values, so tokens
+// with fewer codes, e.g. because an annotator skipped them, do not distort the result
+// The dataMatrix must not be padded with codes for annotations that did not code the token, these are missing values
+func calculateKrippendorffAlpha(dataMatrix []map[int]int) float64 {
+	var valueTotals = map[int]int{}
+	var numberOfPairableValues = float64(0)
+	var observedDisagreement = float64(0)
+	for i, _ := range dataMatrix {
+		var valuesInRow = 0
+		for _, count := range dataMatrix[i] {
+			valuesInRow += count
+		}
+		// Tokens with a single value cannot be paired and are ignored
+		if valuesInRow < 2 {
+			continue
+		}
+		for _, j := range getSortedPositions(dataMatrix[i]) {
+			if dataMatrix[i][j] == 0 {
+				continue
+			}
+			valueTotals[j] += dataMatrix[i][j]
+			observedDisagreement += float64(dataMatrix[i][j]*(valuesInRow-dataMatrix[i][j])) / float64(valuesInRow-1)
+		}
+		numberOfPairableValues += float64(valuesInRow)
+	}
+
+	// Without any pairable values, there is no agreement, as for the other kappas
+	if numberOfPairableValues == 0 {
+		return 0.0
+	}
+	var expectedDisagreement = float64(0)
+	for _, j := range getSortedPositions(valueTotals) {
+		expectedDisagreement += float64(valueTotals[j]) * (numberOfPairableValues - float64(valueTotals[j]))
+	}
+	// This is only for the special case, when denominator is 0
+	if expectedDisagreement == 0.0 {
+		return 1.0
+	}
+	return 1.0 - (numberOfPairableValues-1)*observedDisagreement/expectedDisagreement
+}
+
+// Gwet's AC1 and the multi-rater Scott's pi share the observed agreement and the mean proportions of the codes
+// Both use the proportions of every token, so tokens with more codes do not dominate the chance agreement
+// Deviation from Gwet (2008): the chance agreement is divided by the number of codes that occur in the data minus 1,
+// not by the number of categories of the scheme minus 1. A code combines an open set of word codes with the TORE
+// categories and relationships, so the scheme has no fixed number of categories, and with all possible combinations
+// the chance agreement would vanish and AC1 would only be the observed agreement
+func calculateGwetAC1AndScottPi(dataMatrix []map[int]int) (float64, float64) {
+	var meanProportions = map[int]float64{}
+	var sumOfPi = float64(0)
+	var numberOfTokens = 0
+	for i, _ := range dataMatrix {
+		var sumOfCodesInRow = float64(0)
+		var addedSquaresOfRow = float64(0)
+		for _, count := range dataMatrix[i] {
+			sumOfCodesInRow += float64(count)
+			addedSquaresOfRow += float64(count * count)
+		}
+		// Tokens with less than two codes cannot be agreed upon and are ignored
+		if sumOfCodesInRow < 2 {
+			continue
+		}
+		for j, count := range dataMatrix[i] {
+			meanProportions[j] += float64(count) / sumOfCodesInRow
+		}
+		sumOfPi += (addedSquaresOfRow - sumOfCodesInRow) / (sumOfCodesInRow * (sumOfCodesInRow - 1))
+		numberOfTokens++
+	}
+	var pHead = sumOfPi / float64(numberOfTokens)
+
+	var scottPc = float64(0)
+	var gwetPc = float64(0)
+	var numberOfUsedCodes = 0
+	var positions []int
+	for j := range meanProportions {
+		positions = append(positions, j)
+	}
+	sort.Ints(positions)
+	for _, j := range positions {
+		meanProportions[j] /= float64(numberOfTokens)
+		scottPc += meanProportions[j] * meanProportions[j]
+		gwetPc += meanProportions[j] * (1.0 - meanProportions[j])
+		if meanProportions[j] > 0 {
+			numberOfUsedCodes++
+		}
+	}
+	if numberOfUsedCodes > 1 {
+		gwetPc /= float64(numberOfUsedCodes - 1)
+	}
+
+	var gwetAC1 = (pHead - gwetPc) / (1.0 - gwetPc)
+	var scottPi float64
+	// This is only for the special case, when denominator is 0
+	if (1.0 - scottPc) == 0.0 {
+		scottPi = 1.0
+	} else {
+		scottPi = (pHead - scottPc) / (1.0 - scottPc)
+	}
+	return gwetAC1, scottPi
+}
+
+// Returns the sum of every column of a sparse matrix, columns without codes are left out
+func getSumsOfColumns(dataMatrix []map[int]int) map[int]int {
+	var sumsOfColumns = map[int]int{}
+	for i, _ := range dataMatrix {
+		for j, count := range dataMatrix[i] {
+			sumsOfColumns[j] += count
+		}
+	}
+	return sumsOfColumns
+}
+
+// Returns the positions of a sparse row in ascending order, so floating point sums are always added up in the same order
+func getSortedPositions(row map[int]int) []int {
+	var positions = make([]int, 0, len(row))
+	for j := range row {
+		positions = append(positions, j)
+	}
+	sort.Ints(positions)
+	return positions
 }
 
 // The position in the dataMatrix depends on categories, wordCodes and relationships, which have to be looked up in the respective maps
@@ -110,47 +289,47 @@ func calculatePosition(
 	existingRelsMap map[int]string,
 	numberOfCategories int,
 	numberOfRels int,
-	dataMatrix [][]int,
+	dataMatrix []map[int]int,
 	sumOfAllCells int,
 	dataRow int,
 ) (int, int) {
 	var totalPosition = 0
-	if len(codeAlternative.Code.RelationshipMemberships) != 0 {
-		for _, memberIndex := range codeAlternative.Code.RelationshipMemberships {
-			var categoryPosition = categoryMap[codeAlternative.Code.Tore]
-			var wordCodePosition = wordCodeMap[codeAlternative.Code.Name]
-			var relationshipName = existingRelsMap[*memberIndex]
-			var relationshipPosition = relNameMap[relationshipName]
-			totalPosition = (wordCodePosition * numberOfCategories * numberOfRels) + (categoryPosition * numberOfRels) + relationshipPosition
-			dataMatrix[dataRow][totalPosition] += 1
-			sumOfAllCells++
-		}
-	} else {
-		var categoryPosition = categoryMap[codeAlternative.Code.Tore]
-		var wordCodePosition = wordCodeMap[codeAlternative.Code.Name]
-		var relationshipPosition = 0
-		totalPosition = (wordCodePosition * numberOfCategories * numberOfRels) + (categoryPosition * numberOfRels) + relationshipPosition
+	for _, position := range getCodePositions(codeAlternative, wordCodeMap, categoryMap, relNameMap, existingRelsMap, numberOfCategories, numberOfRels) {
+		totalPosition = position
 		dataMatrix[dataRow][totalPosition] += 1
 		sumOfAllCells++
 	}
 	return totalPosition, sumOfAllCells
 }
 
-func fillDataMatrices(
-	codeAlternatives []CodeAlternatives,
-	agreement Agreement,
+// Returns the positions of a code in a row of the dataMatrix, one for every relationship the code is a member of
+func getCodePositions(
+	codeAlternative CodeAlternatives,
 	wordCodeMap map[string]int,
 	categoryMap map[string]int,
 	relNameMap map[string]int,
 	existingRelsMap map[int]string,
 	numberOfCategories int,
 	numberOfRels int,
-	numberOfAnnotations int,
-	numberOfAlternatives int,
-) ([][]int, [][]int, float64, int) {
-	var tokenMap = map[int][]CodeAlternatives{}
-	var sumOfAllCells = 0
+) []int {
+	var categoryPosition = categoryMap[codeAlternative.Code.Tore]
+	var wordCodePosition = wordCodeMap[codeAlternative.Code.Name]
+	if len(codeAlternative.Code.RelationshipMemberships) == 0 {
+		var relationshipPosition = 0
+		return []int{(wordCodePosition * numberOfCategories * numberOfRels) + (categoryPosition * numberOfRels) + relationshipPosition}
+	}
+	var positions []int
+	for _, memberIndex := range codeAlternative.Code.RelationshipMemberships {
+		var relationshipName = existingRelsMap[*memberIndex]
+		var relationshipPosition = relNameMap[relationshipName]
+		positions = append(positions, (wordCodePosition*numberOfCategories*numberOfRels)+(categoryPosition*numberOfRels)+relationshipPosition)
+	}
+	return positions
+}
 
+// Groups all code alternatives that are not declined by the tokens they contain
+func groupCodeAlternativesByToken(codeAlternatives []CodeAlternatives) map[int][]CodeAlternatives {
+	var tokenMap = map[int][]CodeAlternatives{}
 	for _, codeAlternative := range codeAlternatives {
 		// Ignore declined
 		if codeAlternative.MergeStatus != "Declined" {
@@ -159,13 +338,81 @@ func fillDataMatrices(
 			}
 		}
 	}
+	return tokenMap
+}
+
+// Returns the codes every annotation has for a token. As in fillDataMatrices, annotations without an own code
+// are counted as having assigned the accepted codes of the token
+func getCodesOfAnnotationsForToken(
+	codeAlternativesOfToken []CodeAlternatives,
+	annotationNames []string,
+) map[string][]CodeAlternatives {
+	var codesOfAnnotations = map[string][]CodeAlternatives{}
+	var acceptedCodes []CodeAlternatives
+	for _, codeAlternative := range codeAlternativesOfToken {
+		codesOfAnnotations[codeAlternative.AnnotationName] = append(codesOfAnnotations[codeAlternative.AnnotationName], codeAlternative)
+		if codeAlternative.MergeStatus == "Accepted" {
+			acceptedCodes = append(acceptedCodes, codeAlternative)
+		}
+	}
+	if len(acceptedCodes) != 0 {
+		for _, annotationName := range annotationNames {
+			if len(codesOfAnnotations[annotationName]) == 0 {
+				codesOfAnnotations[annotationName] = acceptedCodes
+			}
+		}
+	}
+	return codesOfAnnotations
+}
+
+// Returns the indices of all coded tokens in the order of agreement.Tokens, which is the order of the rows of the data matrices
+func getCodedTokenIndices(agreement Agreement) []int {
+	var tokenMap = groupCodeAlternativesByToken(agreement.CodeAlternatives)
+	var codedTokenIndices []int
+	for _, token := range agreement.Tokens {
+		if len(tokenMap[*token.Index]) != 0 {
+			codedTokenIndices = append(codedTokenIndices, *token.Index)
+		}
+	}
+	return codedTokenIndices
+}
+
+// Maps the index of every relationship in the agreement to its relationshipName
+func createExistingRelsMap(agreement Agreement) map[int]string {
+	var existingRelsMap = map[int]string{}
+	for _, existingToreRel := range agreement.TORERelationships {
+		if existingToreRel.Index != nil {
+			existingRelsMap[*existingToreRel.Index] = existingToreRel.RelationshipName
+		}
+	}
+	return existingRelsMap
+}
 
-	var dataMatrix = make([][]int, len(tokenMap))
-	var dataMatrixForRowCalculations = make([][]int, len(tokenMap))
+// Fills the dataMatrix and the dataMatrixForRowCalculations, in which annotations without a code for a token are
+// counted as having assigned the accepted code. The reliabilityDataMatrix holds the same rows without these codes,
+// so Krippendorff's alpha sees them as missing values
+func fillDataMatrices(
+	codeAlternatives []CodeAlternatives,
+	agreement Agreement,
+	wordCodeMap map[string]int,
+	categoryMap map[string]int,
+	relNameMap map[string]int,
+	existingRelsMap map[int]string,
+	numberOfCategories int,
+	numberOfRels int,
+	numberOfAnnotations int,
+) ([]map[int]int, []map[int]int, []map[int]int, float64, int) {
+	var tokenMap = groupCodeAlternativesByToken(codeAlternatives)
+	var sumOfAllCells = 0
+
+	var dataMatrix = make([]map[int]int, len(tokenMap))
+	var dataMatrixForRowCalculations = make([]map[int]int, len(tokenMap))
+	var reliabilityDataMatrix = make([]map[int]int, len(tokenMap))
 	for i := 0; i < len(tokenMap); i++ {
-		// looping through the slice to declare slice of slices of correct length
-		dataMatrix[i] = make([]int, numberOfAlternatives)
-		dataMatrixForRowCalculations[i] = make([]int, numberOfAlternatives)
+		// Rows only hold the positions of codes that occur for the token
+		dataMatrix[i] = map[int]int{}
+		dataMatrixForRowCalculations[i] = map[int]int{}
+		reliabilityDataMatrix[i] = map[int]int{}
 	}
 	dataRow := 0
 	for _, token := range agreement.Tokens {
@@ -192,6 +439,9 @@ func fillDataMatrices(
 			}
 			annotationNameSet[codeAlternative.AnnotationName] = true
 		}
+		for position, count := range dataMatrixForRowCalculations[dataRow] {
+			reliabilityDataMatrix[dataRow][position] = count
+		}
 		var numberOfUnassignedCodes = numberOfAnnotations - len(annotationNameSet)
 		if numberOfUnassignedCodes > 0 {
 			dataMatrix[dataRow][acceptedFieldToFill] += numberOfUnassignedCodes
@@ -200,7 +450,7 @@ func fillDataMatrices(
 		}
 		dataRow++
 	}
-	return dataMatrix, dataMatrixForRowCalculations, float64(sumOfAllCells), len(tokenMap)
+	return dataMatrix, dataMatrixForRowCalculations, reliabilityDataMatrix, float64(sumOfAllCells), len(tokenMap)
 }
 
 func getNumberOfRelsCategoriesAndWordCodes(
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func getIntPointer(i int) *int {
+	return &i
+}
+
+func makeTestCodeAlternative(index int, annotationName string, mergeStatus string, tore string, name string, tokens ...int) CodeAlternatives {
+	var tokenPointers = []*int{}
+	for _, token := range tokens {
+		tokenPointers = append(tokenPointers, getIntPointer(token))
+	}
+	return CodeAlternatives{
+		AnnotationName: annotationName,
+		MergeStatus:    mergeStatus,
+		Index:          index,
+		Code: Code{
+			Tokens:                  tokenPointers,
+			Name:                    name,
+			Tore:                    tore,
+			Index:                   getIntPointer(index),
+			RelationshipMemberships: []*int{},
+		},
+	}
+}
+
+func makeTestTokens(numberOfTokens int) []Token {
+	var tokens []Token
+	for i := 0; i < numberOfTokens; i++ {
+		tokens = append(tokens, Token{Index: getIntPointer(i), Name: "token", Lemma: "token", Pos: "NN"})
+	}
+	return tokens
+}
+
+var testToreCategories = ToreCategories{Tores: []string{"Task", "Goal", "Software"}}
+var testToreRelationships = ToreRelationships{RelationshipNames: []string{"refersTo"}}
+
+// Returns an agreement in which every annotation codes every pair of tokens, so no annotation is counted for codes it
+// did not assign. Most codes agree on word code and TORE category, some are members of a relationship
+func makeTestAgreementWithWordCodes(numberOfTokens int, numberOfAnnotations int, numberOfWordCodes int, seed int64) Agreement {
+	var random = rand.New(rand.NewSource(seed))
+	var agreement = Agreement{Tokens: makeTestTokens(numberOfTokens)}
+	for documentBegin := 0; documentBegin < numberOfTokens; documentBegin += 100 {
+		agreement.Docs = append(agreement.Docs, DocWrapper{
+			Name:       fmt.Sprint("document", documentBegin),
+			BeginIndex: getIntPointer(documentBegin),
+			EndIndex:   getIntPointer(documentBegin + 100),
+		})
+	}
+	var wordCodesOfSpans []int
+	for token := 0; token+1 < numberOfTokens; token += 2 {
+		wordCodesOfSpans = append(wordCodesOfSpans, random.Intn(numberOfWordCodes))
+	}
+	for annotation := 0; annotation < numberOfAnnotations; annotation++ {
+		var annotationName = fmt.Sprint("annotation", annotation)
+		agreement.Annotations = append(agreement.Annotations, annotationName)
+		for span, wordCode := range wordCodesOfSpans {
+			if random.Float64() < 0.3 {
+				wordCode = random.Intn(numberOfWordCodes)
+			}
+			var tore = testToreCategories.Tores[wordCode%len(testToreCategories.Tores)]
+			if random.Float64() < 0.2 {
+				tore = testToreCategories.Tores[random.Intn(len(testToreCategories.Tores))]
+			}
+			var index = len(agreement.CodeAlternatives)
+			var codeAlternative = makeTestCodeAlternative(index, annotationName, "Pending", tore, fmt.Sprint("word", wordCode), 2*span, 2*span+1)
+			if random.Float64() < 0.2 {
+				var relationshipIndex = len(agreement.TORERelationships)
+				agreement.TORERelationships = append(agreement.TORERelationships, TORERelationship{
+					TOREEntity:       getIntPointer(index),
+					TargetTokens:     []*int{getIntPointer((2*span + 2) % numberOfTokens)},
+					RelationshipName: testToreRelationships.RelationshipNames[0],
+					Index:            getIntPointer(relationshipIndex),
+				})
+				codeAlternative.Code.RelationshipMemberships = []*int{getIntPointer(relationshipIndex)}
+			}
+			agreement.CodeAlternatives = append(agreement.CodeAlternatives, codeAlternative)
+		}
+	}
+	return agreement
+}
+
+func testFloatsAreEqual(t *testing.T, name string, got float64, want float64) {
+	t.Helper()
+	if math.Abs(got-want) > 1e-9 {
+		t.Errorf("%s = %.17g, want %.17g", name, got, want)
+	}
+}
+
+// C is listed in the agreement but has no code at all. It counts as an annotation that assigned no code,
+// before it was ignored as if the agreement had only two annotations
+func TestGetKappasCountsAnnotationsWithoutCodes(t *testing.T) {
+	var agreement = Agreement{
+		Annotations: []string{"A", "B", "C"},
+		Tokens:      makeTestTokens(4),
+		CodeAlternatives: []CodeAlternatives{
+			makeTestCodeAlternative(0, "A", "Pending", "Task", "x", 0),
+			makeTestCodeAlternative(1, "B", "Pending", "Task", "x", 0),
+			makeTestCodeAlternative(2, "A", "Pending", "Task", "y", 1),
+			makeTestCodeAlternative(3, "B", "Pending", "Goal", "y", 1),
+			makeTestCodeAlternative(4, "A", "Pending", "Software", "z", 2),
+			makeTestCodeAlternative(5, "B", "Pending", "Software", "z", 2),
+		},
+	}
+	var kappas = getKappas(agreement, testToreCategories, testToreRelationships)
+
+	var agreementWithoutC = agreement
+	agreementWithoutC.Annotations = []string{"A", "B"}
+	var kappasWithoutC = getKappas(agreementWithoutC, testToreCategories, testToreRelationships)
+
+	// Fleiss kappa is below 0 and set to 0, because C disagrees with A and B on every token
+	testFloatsAreEqual(t, "fleissKappa", kappas[fleissKappaName], 0)
+	testFloatsAreEqual(t, "brennanKappa", kappas[brennanKappaName], 0.14529914529914528)
+	testFloatsAreEqual(t, "fleissKappaWithoutC", kappasWithoutC[fleissKappaName], 0.53846153846153844)
+	testFloatsAreEqual(t, "brennanKappaWithoutC", kappasWithoutC[brennanKappaName], 0.62015503875968991)
+}
+
+// C has no code at all, so C's values are missing for Krippendorff's alpha instead of being filled in
+func TestKrippendorffAlphaTreatsUncodedAnnotationsAsMissing(t *testing.T) {
+	var agreement = Agreement{
+		Annotations: []string{"A", "B", "C"},
+		Tokens:      makeTestTokens(3),
+		CodeAlternatives: []CodeAlternatives{
+			makeTestCodeAlternative(0, "A", "Pending", "Task", "x", 0),
+			makeTestCodeAlternative(1, "B", "Pending", "Task", "x", 0),
+			makeTestCodeAlternative(2, "A", "Pending", "Goal", "y", 1),
+			makeTestCodeAlternative(3, "B", "Pending", "Goal", "y", 1),
+			makeTestCodeAlternative(4, "A", "Pending", "Task", "x", 2),
+			makeTestCodeAlternative(5, "B", "Pending", "Task", "x", 2),
+		},
+	}
+	var kappas = getKappas(agreement, testToreCategories, testToreRelationships)
+	testFloatsAreEqual(t, "krippendorffAlpha", kappas[krippendorffAlphaName], 1)
+}
+
+// Gwet's chance agreement is divided by the 3 codes used in the data minus 1, see calculateGwetAC1AndScottPi
+func TestCalculateGwetAC1AndScottPiUsesCodesOfTheData(t *testing.T) {
+	var dataMatrix = []map[int]int{
+		{0: 2},
+		{1: 1, 2: 1},
+	}
+	gwetAC1, scottPi := calculateGwetAC1AndScottPi(dataMatrix)
+	testFloatsAreEqual(t, "gwetAC1", gwetAC1, 3.0/11.0)
+	testFloatsAreEqual(t, "scottPi", scottPi, 0.2)
+}
+
+// The values were calculated with the dense data matrices, which had a column for every possible code
+func TestGetKappasMatchesDenseDataMatrices(t *testing.T) {
+	var agreement = makeTestAgreementWithWordCodes(200, 4, 300, 1)
+	var kappas = getKappas(agreement, testToreCategories, testToreRelationships)
+	testFloatsAreEqual(t, "fleissKappa", kappas[fleissKappaName], 0.20382499235252932)
+	testFloatsAreEqual(t, "brennanKappa", kappas[brennanKappaName], 0.20734498697723613)
+	testFloatsAreEqual(t, "krippendorffAlpha", kappas[krippendorffAlphaName], 0.20482021111208826)
+	testFloatsAreEqual(t, "gwetAC1", kappas[gwetAC1Name], 0.20515927736125766)
+	testFloatsAreEqual(t, "scottPi", kappas[scottPiName], 0.20382499235252932)
+}
+
+func BenchmarkGetKappas(b *testing.B) {
+	var agreement = makeTestAgreementWithWordCodes(10000, 5, 500, 1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		getKappas(agreement, testToreCategories, testToreRelationships)
+	}
+}
+
+// The significance is tested on the data matrices of the kappas, so it tests the same fleiss kappa
+func TestGetFleissKappaSignificanceUsesDataMatricesOfKappas(t *testing.T) {
+	var agreement = makeTestAgreementWithWordCodes(200, 4, 300, 1)
+	dataMatrix, dataMatrixForRowCalculation, _ := getDataMatrices(agreement, testToreCategories, testToreRelationships)
+	var significance = getFleissKappaSignificance(dataMatrix, dataMatrixForRowCalculation)
+	testFloatsAreEqual(t, "fleissKappa", significance.FleissKappa, 0.20382499235252932)
+	if significance.StandardError <= 0 || significance.PValue >= 0.05 {
+		t.Errorf("significance = %+v, want a standard error and a p value below 0.05", significance)
+	}
+}
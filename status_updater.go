@@ -1,195 +1,687 @@
 package main
 
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Strategies to decide whether the spans of two codes are the same span
+const (
+	spanMatchingExact = "exact"
+	spanMatchingIoU   = "iou"
+	spanMatchingHead  = "head"
+)
+
+// Positions of the head token in a span, which spanMatchingHead compares
+// The last token is the default, as spans are mostly english noun or verb phrases, in which the head comes last
+const (
+	spanHeadTokenLast  = "last"
+	spanHeadTokenFirst = "first"
+)
+
+// SpanMatching the strategy to match spans, the threshold is only used by spanMatchingIoU and the position of the
+// head token only by spanMatchingHead
+type SpanMatching struct {
+	Strategy          string
+	IoUThreshold      float64
+	HeadTokenPosition string
+}
+
+// Policies to merge a field of codes
+// Must match fields split the candidates, ignored fields are taken if they agree and majority fields are taken
+// from most annotations
+const (
+	mergePolicyMustMatch = "must_match"
+	mergePolicyIgnore    = "ignore"
+	mergePolicyMajority  = "majority"
+)
+
+// Fields of codes with a merge policy
+const (
+	mergeFieldTore          = "tore"
+	mergeFieldName          = "name"
+	mergeFieldRelationships = "relationships"
+)
+
+// MergePolicy the merge policy of every field of codes
+type MergePolicy struct {
+	Tore          string
+	Name          string
+	Relationships string
+}
+
 type CodeMergeCandidate struct {
 	Tokens                    []*int
 	Name                      string
 	Tore                      string
 	RelationshipMemberships   []*int
-	annotationNameOccurrences []string
+	annotationNameOccurrences map[string]bool
+	spanGroup                 int
+	codeAlternativeIndices    []int
+}
+
+// Codes with the same key are the same candidate, fields that do not have to match are left empty
+type codeMergeCandidateKey struct {
+	spanGroup     int
+	tore          string
+	name          string
+	relationships string
 }
 
+// Codes are accepted, if at least requiredNumberOfAnnotations annotations made the same code for the same span
+// With requiredNumberOfAnnotations equal to numberOfAnnotations, all annotations have to agree
 func updateStatusOfCodeAlternatives(
 	codeAlternatives []CodeAlternatives,
 	toreRelationships []TORERelationship,
 	numberOfAnnotations int,
+	requiredNumberOfAnnotations int,
+	spanMatching SpanMatching,
+	mergePolicy MergePolicy,
 ) []CodeAlternatives {
+	var relationshipMap = createRelationshipMapOfList(toreRelationships)
+	var spanGroupsOfCodeAlternatives = getSpanGroupsOfCodeAlternatives(codeAlternatives, spanMatching)
+
+	// Every candidate is one variant of a code for a span, together with all annotations that made it
 	var mergeCandidates []CodeMergeCandidate
-	var rejected [][]*int
-	for _, codeAlternative := range codeAlternatives {
-		if len(mergeCandidates) == 0 {
-			if len(rejected) == 0 {
-				// First candidate is automatically added to mergeCandidates
-				var newCandidate = CodeMergeCandidate{
-					codeAlternative.Code.Tokens,
-					codeAlternative.Code.Name,
-					codeAlternative.Code.Tore,
-					codeAlternative.Code.RelationshipMemberships,
-					[]string{codeAlternative.AnnotationName},
+	var mergeCandidateIndices = map[codeMergeCandidateKey]int{}
+	for i, codeAlternative := range codeAlternatives {
+		var key = getCodeMergeCandidateKey(codeAlternative.Code, spanGroupsOfCodeAlternatives[i], relationshipMap, mergePolicy)
+		j, isFound := mergeCandidateIndices[key]
+		if !isFound {
+			j = len(mergeCandidates)
+			mergeCandidateIndices[key] = j
+			mergeCandidates = append(mergeCandidates, CodeMergeCandidate{
+				Tokens:                    codeAlternative.Code.Tokens,
+				Name:                      codeAlternative.Code.Name,
+				Tore:                      codeAlternative.Code.Tore,
+				RelationshipMemberships:   codeAlternative.Code.RelationshipMemberships,
+				annotationNameOccurrences: map[string]bool{},
+				spanGroup:                 spanGroupsOfCodeAlternatives[i],
+			})
+		}
+		mergeCandidates[j].annotationNameOccurrences[codeAlternative.AnnotationName] = true
+		mergeCandidates[j].codeAlternativeIndices = append(mergeCandidates[j].codeAlternativeIndices, i)
+	}
+	return setCodeMergeStatus(codeAlternatives, relationshipMap, spanGroupsOfCodeAlternatives, mergeCandidates, numberOfAnnotations, requiredNumberOfAnnotations, spanMatching, mergePolicy)
+}
+
+// Returns the key of the candidate of a code, only the fields that must match are part of the key
+func getCodeMergeCandidateKey(
+	code Code,
+	spanGroup int,
+	relationshipMap map[int]TORERelationship,
+	mergePolicy MergePolicy,
+) codeMergeCandidateKey {
+	var key = codeMergeCandidateKey{spanGroup: spanGroup}
+	if mergePolicy.Tore == mergePolicyMustMatch {
+		key.tore = code.Tore
+	}
+	if mergePolicy.Name == mergePolicyMustMatch {
+		key.name = code.Name
+	}
+	if mergePolicy.Relationships == mergePolicyMustMatch {
+		key.relationships = getRelationshipsKey(code.RelationshipMemberships, relationshipMap)
+	}
+	return key
+}
+
+// The relationships an annotation drew from the same source span group are one candidate, so codes with several
+// relationships are merged as a whole. As for codes, a candidate is accepted if it wins by testIsMajorityWinner
+// The relationships of the first annotation of the accepted candidate are accepted, all other relationships from the
+// span group are declined. Span groups are the ones of the code merge
+// The code merge can give an accepted code relationships the relationship merge does not accept, e.g. by the majority of
+// its candidate. Then the relationships of the accepted code are flagged for review
+func updateStatusOfRelationshipAlternatives(
+	relationshipAlternatives []RelationshipAlternatives,
+	toreRelationships []TORERelationship,
+	codeAlternatives []CodeAlternatives,
+	numberOfAnnotations int,
+	requiredNumberOfAnnotations int,
+	spanMatching SpanMatching,
+) []RelationshipAlternatives {
+	var relationshipMap = createRelationshipMapOfList(toreRelationships)
+	var spanGroupsOfCodeAlternatives = getSpanGroupsOfCodeAlternatives(codeAlternatives, spanMatching)
+	var spanGroupsOfCodes = map[int]int{}
+	for i, codeAlternative := range codeAlternatives {
+		spanGroupsOfCodes[codeAlternative.Index] = spanGroupsOfCodeAlternatives[i]
+	}
+
+	// Collect the relationship alternatives of every annotation by the span group of their source code
+	var spanGroups []int
+	var annotationNamesOfSpanGroups = map[int][]string{}
+	var relationshipAlternativesOfSpanGroups = map[int]map[string][]int{}
+	for i, relationshipAlternative := range relationshipAlternatives {
+		toreRelationship, ok := relationshipMap[relationshipAlternative.Index]
+		if !ok || toreRelationship.TOREEntity == nil {
+			continue
+		}
+		spanGroup, ok := spanGroupsOfCodes[*toreRelationship.TOREEntity]
+		if !ok {
+			continue
+		}
+		if _, ok := relationshipAlternativesOfSpanGroups[spanGroup]; !ok {
+			spanGroups = append(spanGroups, spanGroup)
+			relationshipAlternativesOfSpanGroups[spanGroup] = map[string][]int{}
+		}
+		var annotationName = relationshipAlternative.AnnotationName
+		if _, ok := relationshipAlternativesOfSpanGroups[spanGroup][annotationName]; !ok {
+			annotationNamesOfSpanGroups[spanGroup] = append(annotationNamesOfSpanGroups[spanGroup], annotationName)
+		}
+		relationshipAlternativesOfSpanGroups[spanGroup][annotationName] = append(relationshipAlternativesOfSpanGroups[spanGroup][annotationName], i)
+	}
+
+	var winnerKeysOfSpanGroups = map[int]string{}
+	for _, spanGroup := range spanGroups {
+		var candidateKeys []string
+		var annotationNamesOfCandidates = map[string][]string{}
+		for _, annotationName := range annotationNamesOfSpanGroups[spanGroup] {
+			var relationships []TORERelationship
+			for _, index := range relationshipAlternativesOfSpanGroups[spanGroup][annotationName] {
+				relationships = append(relationships, relationshipMap[relationshipAlternatives[index].Index])
+			}
+			var candidateKey = getRelationshipArcsKey(relationships)
+			if _, ok := annotationNamesOfCandidates[candidateKey]; !ok {
+				candidateKeys = append(candidateKeys, candidateKey)
+			}
+			annotationNamesOfCandidates[candidateKey] = append(annotationNamesOfCandidates[candidateKey], annotationName)
+		}
+
+		var winnerKey = ""
+		var hasWinner = false
+		for _, candidateKey := range candidateKeys {
+			var numbersOfCompetitorOccurrences []int
+			for _, competitorKey := range candidateKeys {
+				if competitorKey != candidateKey {
+					numbersOfCompetitorOccurrences = append(numbersOfCompetitorOccurrences, len(annotationNamesOfCandidates[competitorKey]))
 				}
-				mergeCandidates = append(mergeCandidates, newCandidate)
-			} else {
-				// Test, if candidate is already rejected
-				// If yes, nothing happens. If no, add candidate to mergeCandidates
-				mergeCandidates = testCodeRejection(codeAlternative, mergeCandidates, rejected)
 			}
+			if testIsMajorityWinner(len(annotationNamesOfCandidates[candidateKey]), numbersOfCompetitorOccurrences, numberOfAnnotations, requiredNumberOfAnnotations) {
+				winnerKey, hasWinner = candidateKey, true
+				break
+			}
+		}
+		if !hasWinner {
+			continue
+		}
+		winnerKeysOfSpanGroups[spanGroup] = winnerKey
+
+		// Duplicates of an arc in the accepted annotation are declined as well
+		var acceptedAnnotationName = annotationNamesOfCandidates[winnerKey][0]
+		var acceptedArcKeys = map[string]bool{}
+		for _, annotationName := range annotationNamesOfSpanGroups[spanGroup] {
+			for _, index := range relationshipAlternativesOfSpanGroups[spanGroup][annotationName] {
+				var arcKey = getRelationshipArcKey(relationshipMap[relationshipAlternatives[index].Index])
+				if annotationName == acceptedAnnotationName && !acceptedArcKeys[arcKey] {
+					acceptedArcKeys[arcKey] = true
+					relationshipAlternatives[index].MergeStatus = "Accepted"
+				} else {
+					relationshipAlternatives[index].MergeStatus = "Declined"
+				}
+			}
+		}
+	}
+
+	for i, codeAlternative := range codeAlternatives {
+		var spanGroup = spanGroupsOfCodeAlternatives[i]
+		if _, ok := relationshipAlternativesOfSpanGroups[spanGroup]; !ok || codeAlternative.MergeStatus != "Accepted" {
+			continue
+		}
+		var relationships []TORERelationship
+		for _, relationshipIndex := range codeAlternative.Code.RelationshipMemberships {
+			if relationship, ok := relationshipMap[*relationshipIndex]; ok {
+				relationships = append(relationships, relationship)
+			}
+		}
+		if winnerKey, ok := winnerKeysOfSpanGroups[spanGroup]; !ok || winnerKey != getRelationshipArcsKey(relationships) {
+			flagFieldOfCodeAlternativeForReview(&codeAlternatives[i], mergeFieldRelationships, spanMatching)
+		}
+	}
+	return relationshipAlternatives
+}
+
+// Returns a key for the name and the target tokens of a relationship
+func getRelationshipArcKey(relationship TORERelationship) string {
+	return relationship.RelationshipName + "->" + getSpanKey(relationship.TargetTokens)
+}
+
+// Returns a key for the arcs of relationships, that is independent of their order and of duplicates
+func getRelationshipArcsKey(relationships []TORERelationship) string {
+	var arcKeys = []string{}
+	var arcKeySet = map[string]bool{}
+	for _, relationship := range relationships {
+		var arcKey = getRelationshipArcKey(relationship)
+		if !arcKeySet[arcKey] {
+			arcKeySet[arcKey] = true
+			arcKeys = append(arcKeys, arcKey)
+		}
+	}
+	sort.Strings(arcKeys)
+	return strings.Join(arcKeys, ",")
+}
+
+// A candidate wins, if it has at least requiredNumberOfAnnotations annotations, more than every other candidate
+// for the same span, and none of the others has more annotations than are allowed to disagree
+func testIsMajorityWinner(
+	numberOfOccurrences int,
+	numbersOfCompetitorOccurrences []int,
+	numberOfAnnotations int,
+	requiredNumberOfAnnotations int,
+) bool {
+	if numberOfOccurrences < requiredNumberOfAnnotations {
+		return false
+	}
+	for _, numberOfCompetitorOccurrences := range numbersOfCompetitorOccurrences {
+		if numberOfCompetitorOccurrences >= numberOfOccurrences || numberOfCompetitorOccurrences > numberOfAnnotations-requiredNumberOfAnnotations {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the span group of every code alternative
+// Exact spans and head tokens are looked up by key, overlapping spans are grouped by getOverlappingSpanGroups
+func getSpanGroupsOfCodeAlternatives(codeAlternatives []CodeAlternatives, spanMatching SpanMatching) []int {
+	if spanMatching.Strategy == spanMatchingIoU {
+		return getOverlappingSpanGroups(codeAlternatives, spanMatching.IoUThreshold)
+	}
+	var numberOfSpanGroups = 0
+	var spanGroupsOfCodeAlternatives = make([]int, len(codeAlternatives))
+	var spanGroupsOfKeys = map[string]int{}
+	for i, codeAlternative := range codeAlternatives {
+		var tokens = codeAlternative.Code.Tokens
+		var key = getSpanKey(tokens)
+		if spanMatching.Strategy == spanMatchingHead {
+			key = ""
+			if len(tokens) != 0 {
+				key = fmt.Sprint(getHeadToken(tokens, spanMatching.HeadTokenPosition))
+			}
+		}
+		existingSpanGroup, ok := spanGroupsOfKeys[key]
+		// Spans without a head token match no other span
+		if ok && key != "" {
+			spanGroupsOfCodeAlternatives[i] = existingSpanGroup
 		} else {
-			var isFound = false
-			for i, candidate := range mergeCandidates {
-				// Candidate is already in mergeCandidates
-				if testEqSlice(codeAlternative.Code.Tokens, candidate.Tokens) {
-					isFound = true
-					// When any property is different, it is added to rejected, and removed from mergeCandidates
-					if (codeAlternative.Code.Tore != candidate.Tore) || (codeAlternative.Code.Name != candidate.Name) || (!testRelationshipsAreEqual(codeAlternative.Code.RelationshipMemberships, candidate.RelationshipMemberships, toreRelationships)) {
-						rejected = append(rejected, candidate.Tokens)
-						mergeCandidates = append(mergeCandidates[:i], mergeCandidates[i+1:]...)
-						break
-					} else {
-						// if nothing has changed, the annotationName is added
-						var isNew = true
-						for _, annoNameOccurrence := range candidate.annotationNameOccurrences {
-							if annoNameOccurrence == codeAlternative.AnnotationName {
-								isNew = false
-							}
-						}
-						if isNew {
-							mergeCandidates[i].annotationNameOccurrences = append(mergeCandidates[i].annotationNameOccurrences, codeAlternative.AnnotationName)
-							break
-						}
-					}
+			spanGroupsOfCodeAlternatives[i] = numberOfSpanGroups
+			spanGroupsOfKeys[key] = numberOfSpanGroups
+			numberOfSpanGroups++
+		}
+	}
+	return spanGroupsOfCodeAlternatives
+}
+
+// Returns the span group of every code alternative, two spans are in the same group if they are connected by spans
+// that overlap with an IoU of at least the threshold. So the groups do not depend on the order of the code alternatives,
+// only their numbers follow the first code alternative of every group
+func getOverlappingSpanGroups(codeAlternatives []CodeAlternatives, iouThreshold float64) []int {
+	// Every distinct span is looked up once, spans without tokens match no other span
+	var spansOfCodeAlternatives = make([]int, len(codeAlternatives))
+	var spansOfKeys = map[string]int{}
+	var tokensOfSpans [][]*int
+	var spansOfTokens = map[int][]int{}
+	for i, codeAlternative := range codeAlternatives {
+		var tokens = codeAlternative.Code.Tokens
+		var key = getSpanKey(tokens)
+		span, ok := spansOfKeys[key]
+		if !ok || len(tokens) == 0 {
+			span = len(tokensOfSpans)
+			spansOfKeys[key] = span
+			tokensOfSpans = append(tokensOfSpans, tokens)
+			var tokenSet = map[int]bool{}
+			for _, token := range tokens {
+				if !tokenSet[*token] {
+					tokenSet[*token] = true
+					spansOfTokens[*token] = append(spansOfTokens[*token], span)
 				}
 			}
-			// Candidate is not found in mergeCandidates, so either it is new, or it is already rejected
-			if !isFound {
-				mergeCandidates = testCodeRejection(codeAlternative, mergeCandidates, rejected)
+		}
+		spansOfCodeAlternatives[i] = span
+	}
+
+	// Overlapping spans are joined, only spans that share a token can overlap
+	var parentsOfSpans = make([]int, len(tokensOfSpans))
+	for span := range parentsOfSpans {
+		parentsOfSpans[span] = span
+	}
+	var getRootSpan = func(span int) int {
+		for parentsOfSpans[span] != span {
+			parentsOfSpans[span] = parentsOfSpans[parentsOfSpans[span]]
+			span = parentsOfSpans[span]
+		}
+		return span
+	}
+	for span, tokens := range tokensOfSpans {
+		for _, token := range tokens {
+			for _, otherSpan := range spansOfTokens[*token] {
+				if otherSpan >= span {
+					continue
+				}
+				var rootSpan, otherRootSpan = getRootSpan(span), getRootSpan(otherSpan)
+				if rootSpan != otherRootSpan && calculateIoU(tokensOfSpans[otherSpan], tokens) >= iouThreshold {
+					parentsOfSpans[rootSpan] = otherRootSpan
+				}
 			}
 		}
+	}
 
+	var spanGroupsOfCodeAlternatives = make([]int, len(codeAlternatives))
+	var spanGroupsOfRootSpans = map[int]int{}
+	for i, span := range spansOfCodeAlternatives {
+		var rootSpan = getRootSpan(span)
+		spanGroup, ok := spanGroupsOfRootSpans[rootSpan]
+		if !ok {
+			spanGroup = len(spanGroupsOfRootSpans)
+			spanGroupsOfRootSpans[rootSpan] = spanGroup
+		}
+		spanGroupsOfCodeAlternatives[i] = spanGroup
 	}
-	return setCodeMergeStatus(codeAlternatives, mergeCandidates, numberOfAnnotations)
+	return spanGroupsOfCodeAlternatives
 }
 
+// A candidate is accepted, if it wins by testIsMajorityWinner against the other candidates for the same span
+// The code of the accepted candidate with the most frequent span is accepted by acceptCodeMergeCandidate
+// The accepted code takes the agreed fields of the candidate, the others are flagged for review
 func setCodeMergeStatus(
 	codeAlternatives []CodeAlternatives,
+	relationshipMap map[int]TORERelationship,
+	spanGroupsOfCodeAlternatives []int,
 	mergeCandidates []CodeMergeCandidate,
 	numberOfAnnotations int,
+	requiredNumberOfAnnotations int,
+	spanMatching SpanMatching,
+	mergePolicy MergePolicy,
 ) []CodeAlternatives {
+	var mergeCandidatesOfSpanGroups = map[int][]int{}
+	for i, candidate := range mergeCandidates {
+		mergeCandidatesOfSpanGroups[candidate.spanGroup] = append(mergeCandidatesOfSpanGroups[candidate.spanGroup], i)
+	}
+	var codeAlternativesOfSpanGroups = map[int][]int{}
+	for i, spanGroup := range spanGroupsOfCodeAlternatives {
+		codeAlternativesOfSpanGroups[spanGroup] = append(codeAlternativesOfSpanGroups[spanGroup], i)
+	}
 
-	for _, candidate := range mergeCandidates {
-		if len(candidate.annotationNameOccurrences) == numberOfAnnotations {
-			var isAccepted = false
-			for i, codeAlternative := range codeAlternatives {
-				if !isAccepted {
-					if testEqSlice(candidate.Tokens, codeAlternative.Code.Tokens) {
-						codeAlternatives[i].MergeStatus = "Accepted"
-						isAccepted = true
-					}
-				} else {
-					if testEqSlice(candidate.Tokens, codeAlternative.Code.Tokens) {
-						codeAlternatives[i].MergeStatus = "Declined"
-					}
-				}
+	for i, candidate := range mergeCandidates {
+		var numberOfOccurrences = len(candidate.annotationNameOccurrences)
+		if numberOfOccurrences < requiredNumberOfAnnotations {
+			continue
+		}
+		var numbersOfCompetitorOccurrences []int
+		for _, j := range mergeCandidatesOfSpanGroups[candidate.spanGroup] {
+			if i != j {
+				numbersOfCompetitorOccurrences = append(numbersOfCompetitorOccurrences, len(mergeCandidates[j].annotationNameOccurrences))
 			}
 		}
+		if !testIsMajorityWinner(numberOfOccurrences, numbersOfCompetitorOccurrences, numberOfAnnotations, requiredNumberOfAnnotations) {
+			continue
+		}
+
+		var acceptedIndex = getCodeAlternativeWithMostFrequentSpan(codeAlternatives, candidate.codeAlternativeIndices)
+		acceptCodeMergeCandidate(codeAlternatives, acceptedIndex, codeAlternativesOfSpanGroups[candidate.spanGroup], candidate, relationshipMap, spanMatching, mergePolicy)
 	}
 	return codeAlternatives
 }
 
-func testCodeRejection(
-	codeAlternative CodeAlternatives,
-	mergeCandidates []CodeMergeCandidate,
-	rejected [][]*int,
-) []CodeMergeCandidate {
-
-	var isAReject = false
-	for _, reject := range rejected {
-		// if candidate is found in rejected
-		if testEqSlice(codeAlternative.Code.Tokens, reject) {
-			isAReject = true
-			for i, candidate := range mergeCandidates {
-				if testEqSlice(codeAlternative.Code.Tokens, candidate.Tokens) {
-					mergeCandidates = append(mergeCandidates[:i], mergeCandidates[i+1:]...)
-					break
-				}
-			}
-			break
+// Accepts a code of the candidate and declines all other codes of its span group
+// Overlapping span groups can chain spans that do not overlap each other, so with spanMatchingIoU only codes that
+// overlap the accepted code by the threshold are declined or merged, the others stay as they are
+// The accepted code takes the agreed fields of the candidate, and records how it was merged, if the spans only overlap
+// or some fields do not agree
+func acceptCodeMergeCandidate(
+	codeAlternatives []CodeAlternatives,
+	acceptedIndex int,
+	codeAlternativeIndicesOfSpanGroup []int,
+	candidate CodeMergeCandidate,
+	relationshipMap map[int]TORERelationship,
+	spanMatching SpanMatching,
+	mergePolicy MergePolicy,
+) {
+	var testIsMatchingSpan = func(k int) bool {
+		return spanMatching.Strategy != spanMatchingIoU ||
+			calculateIoU(codeAlternatives[acceptedIndex].Code.Tokens, codeAlternatives[k].Code.Tokens) >= spanMatching.IoUThreshold
+	}
+	var chosenSpanKey = getSpanKey(codeAlternatives[acceptedIndex].Code.Tokens)
+	var otherSpans = [][]int{}
+	var otherSpanKeys = map[string]bool{}
+	for _, k := range codeAlternativeIndicesOfSpanGroup {
+		if k == acceptedIndex {
+			codeAlternatives[k].MergeStatus = "Accepted"
+		} else if testIsMatchingSpan(k) {
+			codeAlternatives[k].MergeStatus = "Declined"
+		} else {
+			continue
+		}
+		var spanKey = getSpanKey(codeAlternatives[k].Code.Tokens)
+		if spanKey != chosenSpanKey && !otherSpanKeys[spanKey] {
+			otherSpanKeys[spanKey] = true
+			otherSpans = append(otherSpans, getSortedTokenIndices(codeAlternatives[k].Code.Tokens))
+		}
+	}
+	var matchingCodeAlternativeIndices []int
+	for _, k := range candidate.codeAlternativeIndices {
+		if k == acceptedIndex || testIsMatchingSpan(k) {
+			matchingCodeAlternativeIndices = append(matchingCodeAlternativeIndices, k)
 		}
 	}
-	if !isAReject {
-		var newCandidate = CodeMergeCandidate{
-			codeAlternative.Code.Tokens,
-			codeAlternative.Code.Name,
-			codeAlternative.Code.Tore,
-			codeAlternative.Code.RelationshipMemberships,
-			[]string{codeAlternative.AnnotationName},
+	candidate.codeAlternativeIndices = matchingCodeAlternativeIndices
+	var fieldsToReview = mergeFieldsOfCandidate(codeAlternatives, acceptedIndex, candidate, relationshipMap, mergePolicy)
+	if len(otherSpans) != 0 || len(fieldsToReview) != 0 {
+		codeAlternatives[acceptedIndex].MergeDetails = &MergeDetails{
+			SpanMatchingStrategy: spanMatching.Strategy,
+			ChosenSpan:           getSortedTokenIndices(codeAlternatives[acceptedIndex].Code.Tokens),
+			OtherSpans:           otherSpans,
+			FieldsToReview:       fieldsToReview,
 		}
-		mergeCandidates = append(mergeCandidates, newCandidate)
 	}
-	return mergeCandidates
 }
 
-// Returns true, if two lists of integer-pointers contain the same elements, independent of the order
-func testEqSlice(a, b []*int) bool {
-	if len(a) != len(b) {
-		return false
-	}
-	for i := range a {
-		found := false
-		for j := range b {
-			if *a[i] == *b[j] {
-				found = true
-			}
+// Adds the field to the fields to review of the merge details of the code alternative, unless it is already there
+func flagFieldOfCodeAlternativeForReview(codeAlternative *CodeAlternatives, field string, spanMatching SpanMatching) {
+	if codeAlternative.MergeDetails == nil {
+		codeAlternative.MergeDetails = &MergeDetails{
+			SpanMatchingStrategy: spanMatching.Strategy,
+			ChosenSpan:           getSortedTokenIndices(codeAlternative.Code.Tokens),
+			OtherSpans:           [][]int{},
+			FieldsToReview:       []string{},
 		}
-		if !found {
-			return false
+	}
+	for _, fieldToReview := range codeAlternative.MergeDetails.FieldsToReview {
+		if fieldToReview == field {
+			return
 		}
 	}
-	return true
+	codeAlternative.MergeDetails.FieldsToReview = append(codeAlternative.MergeDetails.FieldsToReview, field)
 }
 
-// Returns true, if two lists of TORERelationships contain the same elements, independent of the order
-func testRelationshipsAreEqual(
-	a []*int,
-	b []*int,
-	relationships []TORERelationship,
-) bool {
-	if len(a) != len(b) {
-		return false
+// Sets the fields of the accepted code to the values the annotations of the candidate agreed on
+// Returns the fields without agreement, they keep the value of the accepted code
+func mergeFieldsOfCandidate(
+	codeAlternatives []CodeAlternatives,
+	acceptedIndex int,
+	candidate CodeMergeCandidate,
+	relationshipMap map[int]TORERelationship,
+	mergePolicy MergePolicy,
+) []string {
+	var fieldsToReview = []string{}
+	var fields = []struct {
+		name     string
+		policy   string
+		getValue func(code Code) string
+	}{
+		{mergeFieldTore, mergePolicy.Tore, func(code Code) string { return code.Tore }},
+		{mergeFieldName, mergePolicy.Name, func(code Code) string { return code.Name }},
+		{mergeFieldRelationships, mergePolicy.Relationships, func(code Code) string {
+			return getRelationshipsKey(code.RelationshipMemberships, relationshipMap)
+		}},
 	}
-	var relationshipsA []TORERelationship
-	var relationshipsB []TORERelationship
-	// Filter all relationships that are null from a, and translate indices to TORERelationships
-	for _, relationshipIndex := range a {
-		for _, relationship := range relationships {
-			if relationship.Index != nil {
-				if *relationshipIndex == *relationship.Index {
-					relationshipsA = append(relationshipsA, relationship)
-				}
+	for _, field := range fields {
+		if field.policy == mergePolicyMustMatch {
+			continue
+		}
+		// Count every value once per annotation, and remember the first code alternative with this value
+		var annotationsOfValues = map[string]map[string]bool{}
+		var codeAlternativeOfValues = map[string]int{}
+		for _, index := range candidate.codeAlternativeIndices {
+			var value = field.getValue(codeAlternatives[index].Code)
+			if _, ok := annotationsOfValues[value]; !ok {
+				annotationsOfValues[value] = map[string]bool{}
+				codeAlternativeOfValues[value] = index
 			}
+			annotationsOfValues[value][codeAlternatives[index].AnnotationName] = true
 		}
-	}
-	// Filter all relationships that are null from b, and translate indices to TORERelationships
-	for _, relationshipIndex := range b {
-		for _, relationship := range relationships {
-			if relationship.Index != nil {
-				if *relationshipIndex == *relationship.Index {
-					relationshipsB = append(relationshipsB, relationship)
+		if len(annotationsOfValues) == 1 {
+			continue
+		}
+		var agreedValue = ""
+		var isAgreed = false
+		if field.policy == mergePolicyMajority {
+			var mostAnnotations = 0
+			for value, annotations := range annotationsOfValues {
+				if len(annotations) > mostAnnotations {
+					agreedValue, mostAnnotations, isAgreed = value, len(annotations), true
+				} else if len(annotations) == mostAnnotations {
+					isAgreed = false
 				}
 			}
 		}
+		if !isAgreed {
+			fieldsToReview = append(fieldsToReview, field.name)
+			continue
+		}
+		var agreedCode = codeAlternatives[codeAlternativeOfValues[agreedValue]].Code
+		switch field.name {
+		case mergeFieldTore:
+			codeAlternatives[acceptedIndex].Code.Tore = agreedCode.Tore
+		case mergeFieldName:
+			codeAlternatives[acceptedIndex].Code.Name = agreedCode.Name
+		case mergeFieldRelationships:
+			swapRelationshipsOfCodeAlternatives(codeAlternatives, acceptedIndex, codeAlternativeOfValues[agreedValue], relationshipMap)
+		}
 	}
-	// Compare relationshipNames and lists of TargetTokens, remove them from b if the same
-	for _, rel1 := range relationshipsA {
-		var indicesToRemove []int
-		for j, rel2 := range relationshipsB {
-			if rel1.RelationshipName == rel2.RelationshipName && testEqSlice(rel1.TargetTokens, rel2.TargetTokens) {
-				indicesToRemove = append(indicesToRemove, j)
-			}
+	return fieldsToReview
+}
+
+// Swaps the relationships of two code alternatives, the relationships are pointed to their new source code
+// So every relationship stays the relationship of exactly one code
+func swapRelationshipsOfCodeAlternatives(
+	codeAlternatives []CodeAlternatives,
+	first int,
+	second int,
+	relationshipMap map[int]TORERelationship,
+) {
+	var relationshipsOfFirst = codeAlternatives[first].Code.RelationshipMemberships
+	var relationshipsOfSecond = codeAlternatives[second].Code.RelationshipMemberships
+	codeAlternatives[first].Code.RelationshipMemberships = relationshipsOfSecond
+	codeAlternatives[second].Code.RelationshipMemberships = relationshipsOfFirst
+	for _, relationshipIndex := range relationshipsOfSecond {
+		if relationship, ok := relationshipMap[*relationshipIndex]; ok && relationship.TOREEntity != nil {
+			*relationship.TOREEntity = codeAlternatives[first].Index
+		}
+	}
+	for _, relationshipIndex := range relationshipsOfFirst {
+		if relationship, ok := relationshipMap[*relationshipIndex]; ok && relationship.TOREEntity != nil {
+			*relationship.TOREEntity = codeAlternatives[second].Index
+		}
+	}
+}
+
+// Returns a key for the relationships of a code, that is independent of the order of the relationships
+// Memberships of relationships that do not exist are ignored
+func getRelationshipsKey(relationshipMemberships []*int, relationshipMap map[int]TORERelationship) string {
+	var relationshipKeys []string
+	for _, relationshipIndex := range relationshipMemberships {
+		if relationship, ok := relationshipMap[*relationshipIndex]; ok {
+			relationshipKeys = append(relationshipKeys, relationship.RelationshipName+"->"+getSpanKey(relationship.TargetTokens))
+		}
+	}
+	sort.Strings(relationshipKeys)
+	return strings.Join(relationshipKeys, ",")
+}
+
+// Returns the merge policy of the request, unknown policies have to match
+func getMergePolicy(torePolicy string, namePolicy string, relationshipsPolicy string) MergePolicy {
+	var getPolicy = func(policy string) string {
+		if policy != mergePolicyIgnore && policy != mergePolicyMajority {
+			return mergePolicyMustMatch
+		}
+		return policy
+	}
+	return MergePolicy{
+		Tore:          getPolicy(torePolicy),
+		Name:          getPolicy(namePolicy),
+		Relationships: getPolicy(relationshipsPolicy),
+	}
+}
+
+// Returns the index of the first code alternative whose span was used most often
+func getCodeAlternativeWithMostFrequentSpan(codeAlternatives []CodeAlternatives, codeAlternativeIndices []int) int {
+	var spanCounts = map[string]int{}
+	for _, index := range codeAlternativeIndices {
+		spanCounts[getSpanKey(codeAlternatives[index].Code.Tokens)]++
+	}
+	var mostFrequentIndex = codeAlternativeIndices[0]
+	for _, index := range codeAlternativeIndices {
+		if spanCounts[getSpanKey(codeAlternatives[index].Code.Tokens)] > spanCounts[getSpanKey(codeAlternatives[mostFrequentIndex].Code.Tokens)] {
+			mostFrequentIndex = index
 		}
-		for _, index := range indicesToRemove {
-			relationshipsB = append(relationshipsB[:index], relationshipsB[index+1:]...)
+	}
+	return mostFrequentIndex
+}
+
+// Returns the span matching of the request, unknown strategies are matched exactly and unknown head token positions
+// take the last token
+func getSpanMatching(strategy string, iouThreshold float64, headTokenPosition string) SpanMatching {
+	if strategy != spanMatchingIoU && strategy != spanMatchingHead {
+		strategy = spanMatchingExact
+	}
+	if iouThreshold <= 0 {
+		iouThreshold = defaultSpanIoUThreshold
+	}
+	if headTokenPosition != spanHeadTokenFirst {
+		headTokenPosition = spanHeadTokenLast
+	}
+	return SpanMatching{Strategy: strategy, IoUThreshold: iouThreshold, HeadTokenPosition: headTokenPosition}
+}
+
+// Returns the first or the last token of a span, see spanHeadTokenLast
+func getHeadToken(tokens []*int, headTokenPosition string) int {
+	var head = *tokens[0]
+	for _, token := range tokens {
+		if (headTokenPosition == spanHeadTokenFirst && *token < head) || (headTokenPosition != spanHeadTokenFirst && *token > head) {
+			head = *token
 		}
-		indicesToRemove = []int{}
 	}
-	// If no more elements in b, the two lists are the same
-	return len(relationshipsB) == 0
+	return head
+}
+
+// Returns the indices of the tokens in ascending order
+func getSortedTokenIndices(tokens []*int) []int {
+	var tokenIndices = []int{}
+	for _, token := range tokens {
+		tokenIndices = append(tokenIndices, *token)
+	}
+	sort.Ints(tokenIndices)
+	return tokenIndices
+}
+
+// Returns the number of annotations that have to agree on a code. The threshold is either a number of annotations
+// or a percentage of all annotations, without a threshold all annotations have to agree
+func getRequiredNumberOfAnnotations(
+	majorityThreshold float64,
+	majorityPercentage float64,
+	numberOfAnnotations int,
+) int {
+	var requiredNumberOfAnnotations = numberOfAnnotations
+	if majorityThreshold > 0 {
+		requiredNumberOfAnnotations = int(math.Ceil(majorityThreshold))
+	} else if majorityPercentage > 0 {
+		requiredNumberOfAnnotations = int(math.Ceil(majorityPercentage / 100.0 * float64(numberOfAnnotations)))
+	}
+	if requiredNumberOfAnnotations < 1 {
+		requiredNumberOfAnnotations = 1
+	}
+	if requiredNumberOfAnnotations > numberOfAnnotations {
+		requiredNumberOfAnnotations = numberOfAnnotations
+	}
+	return requiredNumberOfAnnotations
 }
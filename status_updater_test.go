@@ -0,0 +1,353 @@
+package main
+
+import (
+	"testing"
+)
+
+// Returns whether the code alternatives at both indices are in the same span group
+func testHaveSameSpanGroup(spanGroups []int, codeAlternatives []CodeAlternatives, first int, second int) bool {
+	var spanGroupsOfIndices = map[int]int{}
+	for i, codeAlternative := range codeAlternatives {
+		spanGroupsOfIndices[codeAlternative.Index] = spanGroups[i]
+	}
+	return spanGroupsOfIndices[first] == spanGroupsOfIndices[second]
+}
+
+// [1 2] overlaps [0 1] and [2 3] with an IoU of 1/3, [0 1] and [2 3] do not overlap. All three are connected by [1 2],
+// so they are one group in every order
+func TestGetSpanGroupsOfCodeAlternativesWithIoUIsIndependentOfOrder(t *testing.T) {
+	var first = makeTestCodeAlternative(0, "A", "Pending", "Task", "x", 0, 1)
+	var middle = makeTestCodeAlternative(1, "B", "Pending", "Task", "x", 1, 2)
+	var last = makeTestCodeAlternative(2, "C", "Pending", "Task", "x", 2, 3)
+	var spanMatching = getSpanMatching(spanMatchingIoU, 0.3, "")
+	var orders = [][]CodeAlternatives{
+		{first, middle, last},
+		{first, last, middle},
+		{middle, first, last},
+		{middle, last, first},
+		{last, first, middle},
+		{last, middle, first},
+	}
+	for _, codeAlternatives := range orders {
+		var spanGroups = getSpanGroupsOfCodeAlternatives(codeAlternatives, spanMatching)
+		if !testHaveSameSpanGroup(spanGroups, codeAlternatives, 0, 1) || !testHaveSameSpanGroup(spanGroups, codeAlternatives, 1, 2) {
+			t.Errorf("order %d, %d, %d: span groups = %v, want one group", codeAlternatives[0].Index, codeAlternatives[1].Index, codeAlternatives[2].Index, spanGroups)
+		}
+	}
+
+	// Without the connecting span, both spans stay apart
+	var spanGroups = getSpanGroupsOfCodeAlternatives([]CodeAlternatives{first, last}, spanMatching)
+	if spanGroups[0] == spanGroups[1] {
+		t.Errorf("span groups = %v, want two groups", spanGroups)
+	}
+}
+
+// The chain [0 1], [1 2], [2 3] is one span group, but [2 3] does not overlap the accepted [0 1]
+// So only [1 2] is declined and merged, and D's code on [2 3] stays pending
+func TestUpdateStatusOfCodeAlternativesWithIoUOnlyDeclinesOverlappingSpans(t *testing.T) {
+	var codeAlternatives = []CodeAlternatives{
+		makeTestCodeAlternative(0, "A", "Pending", "Task", "x", 0, 1),
+		makeTestCodeAlternative(1, "B", "Pending", "Task", "x", 0, 1),
+		makeTestCodeAlternative(2, "C", "Pending", "Task", "x", 1, 2),
+		makeTestCodeAlternative(3, "D", "Pending", "Task", "x", 2, 3),
+	}
+	var mergePolicy = getMergePolicy(mergePolicyMustMatch, mergePolicyMustMatch, mergePolicyMustMatch)
+	codeAlternatives = updateStatusOfCodeAlternatives(codeAlternatives, nil, 4, 2, getSpanMatching(spanMatchingIoU, 0.3, ""), mergePolicy)
+
+	var wantStatuses = []string{"Accepted", "Declined", "Declined", "Pending"}
+	for i, codeAlternative := range codeAlternatives {
+		if codeAlternative.MergeStatus != wantStatuses[i] {
+			t.Errorf("status of %d = %s, want %s", i, codeAlternative.MergeStatus, wantStatuses[i])
+		}
+	}
+	var mergeDetails = codeAlternatives[0].MergeDetails
+	if mergeDetails == nil || len(mergeDetails.OtherSpans) != 1 || len(mergeDetails.OtherSpans[0]) != 2 || mergeDetails.OtherSpans[0][0] != 1 || mergeDetails.OtherSpans[0][1] != 2 {
+		t.Errorf("merge details = %+v, want the other span [1 2]", mergeDetails)
+	}
+}
+
+func TestGetSpanGroupsOfCodeAlternativesWithHeadToken(t *testing.T) {
+	var codeAlternatives = []CodeAlternatives{
+		makeTestCodeAlternative(0, "A", "Pending", "Task", "x", 0, 1),
+		makeTestCodeAlternative(1, "B", "Pending", "Task", "x", 1),
+		makeTestCodeAlternative(2, "C", "Pending", "Task", "x", 0),
+	}
+	var tests = []struct {
+		headTokenPosition      string
+		wantSameGroupAsFirstOf int
+	}{
+		{"", 1},
+		{spanHeadTokenLast, 1},
+		{spanHeadTokenFirst, 2},
+	}
+	for _, test := range tests {
+		var spanGroups = getSpanGroupsOfCodeAlternatives(codeAlternatives, getSpanMatching(spanMatchingHead, 0, test.headTokenPosition))
+		if !testHaveSameSpanGroup(spanGroups, codeAlternatives, 0, test.wantSameGroupAsFirstOf) {
+			t.Errorf("head token %q: span groups = %v, want %d in the group of 0", test.headTokenPosition, spanGroups, test.wantSameGroupAsFirstOf)
+		}
+		if testHaveSameSpanGroup(spanGroups, codeAlternatives, 1, 2) {
+			t.Errorf("head token %q: span groups = %v, want 1 and 2 apart", test.headTokenPosition, spanGroups)
+		}
+	}
+}
+
+// Returns a relationship of the code alternative with the index to the target token
+func makeTestRelationship(index int, codeAlternativeIndex int, relationshipName string, targetToken int) TORERelationship {
+	return TORERelationship{
+		TOREEntity:       getIntPointer(codeAlternativeIndex),
+		TargetTokens:     []*int{getIntPointer(targetToken)},
+		RelationshipName: relationshipName,
+		Index:            getIntPointer(index),
+	}
+}
+
+// The code of A is accepted, but B and C agree on another relationship. A takes the relationship of B, which has to
+// point to the code of A, and B keeps the former relationship of A
+func TestUpdateStatusOfCodeAlternativesPointsMajorityRelationshipsToAcceptedCode(t *testing.T) {
+	var codeAlternatives = []CodeAlternatives{
+		makeTestCodeAlternative(0, "A", "Pending", "Task", "x", 0, 1),
+		makeTestCodeAlternative(1, "B", "Pending", "Task", "x", 0, 1),
+		makeTestCodeAlternative(2, "C", "Pending", "Task", "x", 0, 1),
+	}
+	var toreRelationships = []TORERelationship{
+		makeTestRelationship(0, 0, "relatesTo", 4),
+		makeTestRelationship(1, 1, "refersTo", 3),
+		makeTestRelationship(2, 2, "refersTo", 3),
+	}
+	for i := range codeAlternatives {
+		codeAlternatives[i].Code.RelationshipMemberships = []*int{getIntPointer(i)}
+	}
+	var mergePolicy = getMergePolicy(mergePolicyMustMatch, mergePolicyMustMatch, mergePolicyMajority)
+	codeAlternatives = updateStatusOfCodeAlternatives(codeAlternatives, toreRelationships, 3, 2, getSpanMatching(spanMatchingExact, 0, ""), mergePolicy)
+
+	if codeAlternatives[0].MergeStatus != "Accepted" {
+		t.Fatalf("merge status of A = %s, want Accepted", codeAlternatives[0].MergeStatus)
+	}
+	var memberships = codeAlternatives[0].Code.RelationshipMemberships
+	if len(memberships) != 1 || *memberships[0] != 1 {
+		t.Fatalf("relationships of A = %v, want the relationship 1 of B", memberships)
+	}
+	if *toreRelationships[1].TOREEntity != 0 {
+		t.Errorf("source of relationship 1 = %d, want the code of A", *toreRelationships[1].TOREEntity)
+	}
+	memberships = codeAlternatives[1].Code.RelationshipMemberships
+	if len(memberships) != 1 || *memberships[0] != 0 || *toreRelationships[0].TOREEntity != 1 {
+		t.Errorf("relationships of B = %v with source %d, want the relationship 0 with the code of B", memberships, *toreRelationships[0].TOREEntity)
+	}
+	if *toreRelationships[2].TOREEntity != 2 {
+		t.Errorf("source of relationship 2 = %d, want the code of C", *toreRelationships[2].TOREEntity)
+	}
+}
+
+// The expected statuses are the results of the merge before the span keys, which compared the tokens of spans one by one
+// Only spans with the same tokens as a multiset match now, before [1 1 2] matched [1 2 2]
+func TestUpdateStatusOfCodeAlternativesWithExactSpans(t *testing.T) {
+	var tests = []struct {
+		name                        string
+		numberOfAnnotations         int
+		requiredNumberOfAnnotations int
+		codeAlternatives            []CodeAlternatives
+		want                        []string
+	}{
+		{"unanimous", 3, 3, []CodeAlternatives{
+			makeTestCodeAlternative(0, "A", "Pending", "Task", "x", 0, 1),
+			makeTestCodeAlternative(1, "B", "Pending", "Task", "x", 0, 1),
+			makeTestCodeAlternative(2, "C", "Pending", "Task", "x", 0, 1),
+		}, []string{"Accepted", "Declined", "Declined"}},
+		{"majority", 3, 2, []CodeAlternatives{
+			makeTestCodeAlternative(0, "A", "Pending", "Task", "x", 0),
+			makeTestCodeAlternative(1, "B", "Pending", "Goal", "x", 0),
+			makeTestCodeAlternative(2, "C", "Pending", "Task", "x", 0),
+		}, []string{"Accepted", "Declined", "Declined"}},
+		{"tie", 4, 2, []CodeAlternatives{
+			makeTestCodeAlternative(0, "A", "Pending", "Task", "x", 0),
+			makeTestCodeAlternative(1, "B", "Pending", "Task", "x", 0),
+			makeTestCodeAlternative(2, "C", "Pending", "Goal", "x", 0),
+			makeTestCodeAlternative(3, "D", "Pending", "Goal", "x", 0),
+		}, []string{"Pending", "Pending", "Pending", "Pending"}},
+		{"order of tokens", 2, 2, []CodeAlternatives{
+			makeTestCodeAlternative(0, "A", "Pending", "Task", "x", 0, 1),
+			makeTestCodeAlternative(1, "B", "Pending", "Task", "x", 1, 0),
+		}, []string{"Accepted", "Declined"}},
+		{"overlapping spans", 2, 2, []CodeAlternatives{
+			makeTestCodeAlternative(0, "A", "Pending", "Task", "x", 0, 1),
+			makeTestCodeAlternative(1, "B", "Pending", "Task", "x", 1, 2),
+		}, []string{"Pending", "Pending"}},
+		{"below threshold", 3, 2, []CodeAlternatives{
+			makeTestCodeAlternative(0, "A", "Pending", "Task", "x", 0),
+			makeTestCodeAlternative(1, "B", "Pending", "Goal", "x", 0),
+			makeTestCodeAlternative(2, "C", "Pending", "Software", "x", 0),
+		}, []string{"Pending", "Pending", "Pending"}},
+		{"same code twice in one annotation", 2, 2, []CodeAlternatives{
+			makeTestCodeAlternative(0, "A", "Pending", "Task", "x", 0),
+			makeTestCodeAlternative(1, "A", "Pending", "Task", "x", 0),
+			makeTestCodeAlternative(2, "B", "Pending", "Goal", "x", 0),
+		}, []string{"Pending", "Pending", "Pending"}},
+		{"several spans", 3, 2, []CodeAlternatives{
+			makeTestCodeAlternative(0, "A", "Pending", "Task", "x", 0),
+			makeTestCodeAlternative(1, "B", "Pending", "Task", "x", 0),
+			makeTestCodeAlternative(2, "A", "Pending", "Goal", "y", 2, 3),
+			makeTestCodeAlternative(3, "C", "Pending", "Goal", "y", 2, 3),
+			makeTestCodeAlternative(4, "B", "Pending", "Goal", "y", 3),
+		}, []string{"Accepted", "Declined", "Accepted", "Declined", "Pending"}},
+		{"multiset of tokens", 2, 2, []CodeAlternatives{
+			makeTestCodeAlternative(0, "A", "Pending", "Task", "x", 1, 1, 2),
+			makeTestCodeAlternative(1, "B", "Pending", "Task", "x", 1, 2, 2),
+		}, []string{"Pending", "Pending"}},
+	}
+	for _, test := range tests {
+		var codeAlternatives = updateStatusOfCodeAlternatives(test.codeAlternatives, nil, test.numberOfAnnotations, test.requiredNumberOfAnnotations, getSpanMatching(spanMatchingExact, 0, ""), getMergePolicy("", "", ""))
+		for i, codeAlternative := range codeAlternatives {
+			if codeAlternative.MergeStatus != test.want[i] {
+				t.Errorf("%s: merge status of %d = %s, want %s", test.name, i, codeAlternative.MergeStatus, test.want[i])
+			}
+		}
+	}
+}
+
+// The expected numbers of statuses are the results of the merge before the span keys
+func TestUpdateStatusOfCodeAlternativesWithExactSpansOfLargerAgreement(t *testing.T) {
+	var tests = []struct {
+		requiredNumberOfAnnotations int
+		want                        map[string]int
+	}{
+		{2, map[string]int{"Accepted": 65, "Declined": 195, "Pending": 140}},
+		{3, map[string]int{"Accepted": 27, "Declined": 81, "Pending": 292}},
+		{4, map[string]int{"Accepted": 2, "Declined": 6, "Pending": 392}},
+	}
+	for _, test := range tests {
+		var agreement = makeTestAgreementWithWordCodes(200, 4, 30, 1)
+		var codeAlternatives = updateStatusOfCodeAlternatives(agreement.CodeAlternatives, agreement.TORERelationships, 4, test.requiredNumberOfAnnotations, getSpanMatching(spanMatchingExact, 0, ""), getMergePolicy("", "", ""))
+		var numbersOfStatuses = map[string]int{}
+		for _, codeAlternative := range codeAlternatives {
+			numbersOfStatuses[codeAlternative.MergeStatus]++
+		}
+		for status, want := range test.want {
+			if numbersOfStatuses[status] != want {
+				t.Errorf("%d required: number of %s = %d, want %d", test.requiredNumberOfAnnotations, status, numbersOfStatuses[status], want)
+			}
+		}
+	}
+}
+
+func BenchmarkUpdateStatusOfCodeAlternatives(b *testing.B) {
+	var agreement = makeTestAgreementWithWordCodes(20000, 5, 30, 1)
+	for _, strategy := range []string{spanMatchingExact, spanMatchingIoU, spanMatchingHead} {
+		b.Run(strategy, func(b *testing.B) {
+			var codeAlternatives = make([]CodeAlternatives, len(agreement.CodeAlternatives))
+			for i := 0; i < b.N; i++ {
+				copy(codeAlternatives, agreement.CodeAlternatives)
+				updateStatusOfCodeAlternatives(codeAlternatives, agreement.TORERelationships, 5, 3, getSpanMatching(strategy, 0, ""), getMergePolicy("", "", ""))
+			}
+		})
+	}
+}
+
+func TestUpdateStatusOfRelationshipAlternatives(t *testing.T) {
+	var tests = []struct {
+		name              string
+		spanMatching      SpanMatching
+		codeTokens        [][]int
+		toreRelationships []TORERelationship
+		want              []string
+	}{
+		// A and B agree, the competing target of C is declined
+		{"majority", getSpanMatching(spanMatchingExact, 0, ""), [][]int{{0}, {0}, {0}}, []TORERelationship{
+			makeTestRelationship(0, 0, "refersTo", 3),
+			makeTestRelationship(1, 1, "refersTo", 3),
+			makeTestRelationship(2, 2, "refersTo", 4),
+		}, []string{"Accepted", "Declined", "Declined"}},
+		// A competing name is declined as well
+		{"other name", getSpanMatching(spanMatchingExact, 0, ""), [][]int{{0}, {0}, {0}}, []TORERelationship{
+			makeTestRelationship(0, 0, "refersTo", 3),
+			makeTestRelationship(1, 1, "relatesTo", 3),
+			makeTestRelationship(2, 2, "refersTo", 3),
+		}, []string{"Accepted", "Declined", "Declined"}},
+		// All three disagree, so nothing is decided
+		{"no majority", getSpanMatching(spanMatchingExact, 0, ""), [][]int{{0}, {0}, {0}}, []TORERelationship{
+			makeTestRelationship(0, 0, "refersTo", 3),
+			makeTestRelationship(1, 1, "refersTo", 4),
+			makeTestRelationship(2, 2, "refersTo", 5),
+		}, []string{"Pending", "Pending", "Pending"}},
+		// A and B agree on both relationships of their codes, which are merged as a whole
+		{"several relationships", getSpanMatching(spanMatchingExact, 0, ""), [][]int{{0}, {0}, {0}}, []TORERelationship{
+			makeTestRelationship(0, 0, "refersTo", 3),
+			makeTestRelationship(1, 0, "relatesTo", 4),
+			makeTestRelationship(2, 1, "relatesTo", 4),
+			makeTestRelationship(3, 1, "refersTo", 3),
+			makeTestRelationship(4, 2, "refersTo", 3),
+		}, []string{"Accepted", "Accepted", "Declined", "Declined", "Declined"}},
+		// The sources only overlap, they are the same source with iou, but not with exact spans
+		{"overlapping sources with iou", getSpanMatching(spanMatchingIoU, 0.5, ""), [][]int{{0, 1}, {0, 1, 2}, {0, 1}}, []TORERelationship{
+			makeTestRelationship(0, 0, "refersTo", 3),
+			makeTestRelationship(1, 1, "refersTo", 3),
+			makeTestRelationship(2, 2, "refersTo", 4),
+		}, []string{"Accepted", "Declined", "Declined"}},
+		{"overlapping sources with exact spans", getSpanMatching(spanMatchingExact, 0, ""), [][]int{{0, 1}, {0, 1, 2}, {0, 1}}, []TORERelationship{
+			makeTestRelationship(0, 0, "refersTo", 3),
+			makeTestRelationship(1, 1, "refersTo", 3),
+			makeTestRelationship(2, 2, "refersTo", 4),
+		}, []string{"Pending", "Pending", "Pending"}},
+	}
+	for _, test := range tests {
+		var codeAlternatives []CodeAlternatives
+		for i, tokens := range test.codeTokens {
+			codeAlternatives = append(codeAlternatives, makeTestCodeAlternative(i, string(rune('A'+i)), "Pending", "Task", "x", tokens...))
+		}
+		var relationshipAlternatives = initializeRelationshipAlternatives(test.toreRelationships, codeAlternatives)
+		relationshipAlternatives = updateStatusOfRelationshipAlternatives(relationshipAlternatives, test.toreRelationships, codeAlternatives, 3, 2, test.spanMatching)
+		for i, relationshipAlternative := range relationshipAlternatives {
+			if relationshipAlternative.MergeStatus != test.want[i] {
+				t.Errorf("%s: merge status of %d = %s, want %s", test.name, i, relationshipAlternative.MergeStatus, test.want[i])
+			}
+		}
+	}
+}
+
+// A, B and C made the code of [0 1], D and E did not. The code merge takes the relationship of A and B by majority of
+// the candidate. With 3 required annotations the relationship merge does not accept it, so it is flagged for review
+func TestUpdateStatusOfRelationshipAlternativesFlagsRelationshipsItDoesNotAccept(t *testing.T) {
+	for _, test := range []struct {
+		requiredNumberOfAnnotations int
+		wantStatus                  string
+		wantIsFlagged               bool
+	}{
+		{2, "Accepted", false},
+		{3, "Pending", true},
+	} {
+		var codeAlternatives = []CodeAlternatives{
+			makeTestCodeAlternative(0, "A", "Pending", "Task", "x", 0, 1),
+			makeTestCodeAlternative(1, "B", "Pending", "Task", "x", 0, 1),
+			makeTestCodeAlternative(2, "C", "Pending", "Task", "x", 0, 1),
+		}
+		var toreRelationships = []TORERelationship{
+			makeTestRelationship(0, 0, "refersTo", 3),
+			makeTestRelationship(1, 1, "refersTo", 3),
+			makeTestRelationship(2, 2, "relatesTo", 4),
+		}
+		for i := range codeAlternatives {
+			codeAlternatives[i].Code.RelationshipMemberships = []*int{getIntPointer(i)}
+		}
+		var spanMatching = getSpanMatching(spanMatchingExact, 0, "")
+		var mergePolicy = getMergePolicy(mergePolicyMustMatch, mergePolicyMustMatch, mergePolicyMajority)
+		codeAlternatives = updateStatusOfCodeAlternatives(codeAlternatives, toreRelationships, 5, test.requiredNumberOfAnnotations, spanMatching, mergePolicy)
+		var relationshipAlternatives = initializeRelationshipAlternatives(toreRelationships, codeAlternatives)
+		relationshipAlternatives = updateStatusOfRelationshipAlternatives(relationshipAlternatives, toreRelationships, codeAlternatives, 5, test.requiredNumberOfAnnotations, spanMatching)
+
+		if codeAlternatives[0].MergeStatus != "Accepted" {
+			t.Fatalf("required %d: merge status of A = %s, want Accepted", test.requiredNumberOfAnnotations, codeAlternatives[0].MergeStatus)
+		}
+		if relationshipAlternatives[0].MergeStatus != test.wantStatus {
+			t.Errorf("required %d: merge status of the relationship of A = %s, want %s", test.requiredNumberOfAnnotations, relationshipAlternatives[0].MergeStatus, test.wantStatus)
+		}
+		var isFlagged = false
+		if mergeDetails := codeAlternatives[0].MergeDetails; mergeDetails != nil {
+			for _, field := range mergeDetails.FieldsToReview {
+				isFlagged = isFlagged || field == mergeFieldRelationships
+			}
+		}
+		if isFlagged != test.wantIsFlagged {
+			t.Errorf("required %d: relationships flagged for review = %t, want %t", test.requiredNumberOfAnnotations, isFlagged, test.wantIsFlagged)
+		}
+	}
+}
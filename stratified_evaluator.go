@@ -0,0 +1,97 @@
+package main
+
+import (
+	"sort"
+)
+
+const defaultStratumLemmaLimit = 20
+
+// StratumAgreement model, the kappas of all coded tokens with the same part-of-speech tag or lemma
+type StratumAgreement struct {
+	Stratum             string             `json:"stratum"`
+	NumberOfTokens      int                `json:"number_of_tokens"`
+	NumberOfCodedTokens int                `json:"number_of_coded_tokens"`
+	Kappas              map[string]float64 `json:"kappas"`
+}
+
+// StratifiedAgreements model, the agreement by part-of-speech tag and by the most frequently coded lemmas
+type StratifiedAgreements struct {
+	Pos   []StratumAgreement `json:"pos"`
+	Lemma []StratumAgreement `json:"lemma"`
+}
+
+func getStratifiedAgreements(
+	agreement Agreement,
+	toreCategories ToreCategories,
+	toreRelationships ToreRelationships,
+	lemmaLimit int,
+) StratifiedAgreements {
+	if lemmaLimit <= 0 {
+		lemmaLimit = defaultStratumLemmaLimit
+	}
+	dataMatrix, dataMatrixForRowCalculation, reliabilityDataMatrix := getDataMatrices(agreement, toreCategories, toreRelationships)
+	var rowOfToken = map[int]int{}
+	for row, tokenIndex := range getCodedTokenIndices(agreement) {
+		rowOfToken[tokenIndex] = row
+	}
+
+	var lemmaAgreements = getStratumAgreements(agreement, dataMatrix, dataMatrixForRowCalculation, reliabilityDataMatrix, rowOfToken, func(token Token) string { return token.Lemma })
+	if len(lemmaAgreements) > lemmaLimit {
+		lemmaAgreements = lemmaAgreements[:lemmaLimit]
+	}
+	return StratifiedAgreements{
+		Pos:   getStratumAgreements(agreement, dataMatrix, dataMatrixForRowCalculation, reliabilityDataMatrix, rowOfToken, func(token Token) string { return token.Pos }),
+		Lemma: lemmaAgreements,
+	}
+}
+
+// Groups the rows of the data matrices by the stratum of their token and calculates the kappas of every group
+// Strata without coded tokens are left out, the others are sorted by their number of coded tokens
+func getStratumAgreements(
+	agreement Agreement,
+	dataMatrix []map[int]int,
+	dataMatrixForRowCalculation []map[int]int,
+	reliabilityDataMatrix []map[int]int,
+	rowOfToken map[int]int,
+	getStratum func(Token) string,
+) []StratumAgreement {
+	var numberOfTokensOfStrata = map[string]int{}
+	var rowsOfStrata = map[string][]int{}
+	var strata []string
+	for _, token := range agreement.Tokens {
+		var stratum = getStratum(token)
+		if _, ok := numberOfTokensOfStrata[stratum]; !ok {
+			strata = append(strata, stratum)
+		}
+		numberOfTokensOfStrata[stratum]++
+		if row, ok := rowOfToken[*token.Index]; ok {
+			rowsOfStrata[stratum] = append(rowsOfStrata[stratum], row)
+		}
+	}
+
+	var stratumAgreements = []StratumAgreement{}
+	for _, stratum := range strata {
+		var rows = rowsOfStrata[stratum]
+		if len(rows) == 0 {
+			continue
+		}
+		var stratumDataMatrix []map[int]int
+		var stratumDataMatrixForRowCalculation []map[int]int
+		var stratumReliabilityDataMatrix []map[int]int
+		for _, row := range rows {
+			stratumDataMatrix = append(stratumDataMatrix, dataMatrix[row])
+			stratumDataMatrixForRowCalculation = append(stratumDataMatrixForRowCalculation, dataMatrixForRowCalculation[row])
+			stratumReliabilityDataMatrix = append(stratumReliabilityDataMatrix, reliabilityDataMatrix[row])
+		}
+		stratumAgreements = append(stratumAgreements, StratumAgreement{
+			Stratum:             stratum,
+			NumberOfTokens:      numberOfTokensOfStrata[stratum],
+			NumberOfCodedTokens: len(rows),
+			Kappas:              calculateKappasFromDataMatrices(stratumDataMatrix, stratumDataMatrixForRowCalculation, stratumReliabilityDataMatrix),
+		})
+	}
+	sort.SliceStable(stratumAgreements, func(i, j int) bool {
+		return stratumAgreements[i].NumberOfCodedTokens > stratumAgreements[j].NumberOfCodedTokens
+	})
+	return stratumAgreements
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Category of annotations without a code on a token in the rows of Fleiss kappa. It cannot be a tore, so it has
+// a distance of 1 to every category
+const categoryOfUncodedAnnotations = "\x00uncoded"
+
+// ToreCategoryDistances distances between two tore categories from 0 (same) to 1 (completely different)
+// Pairs that are not listed have a distance of 1, the order of the two categories does not matter
+type ToreCategoryDistances map[string]map[string]float64
+
+// WeightedKappas model, agreement on tore categories in which close categories count as partial agreement
+type WeightedKappas struct {
+	FleissKappa       float64 `json:"fleiss_kappa"`
+	KrippendorffAlpha float64 `json:"krippendorff_alpha"`
+}
+
+// Returns the distances from the request, or the configured ones if the request contains none
+func getToreCategoryDistances(requestDistances ToreCategoryDistances) (ToreCategoryDistances, error) {
+	if len(requestDistances) != 0 {
+		return requestDistances, testToreCategoryDistancesAreValid(requestDistances)
+	}
+	var distances ToreCategoryDistances
+	err := readConfigurationFile(toreCategoryDistancesFile, &distances)
+	if err != nil {
+		return distances, err
+	}
+	return distances, testToreCategoryDistancesAreValid(distances)
+}
+
+// Returns an error for distances outside of [0, 1], they would give negative agreement weights
+func testToreCategoryDistancesAreValid(distances ToreCategoryDistances) error {
+	for a, distancesOfA := range distances {
+		for b, distance := range distancesOfA {
+			if distance < 0 || distance > 1 {
+				return fmt.Errorf("distance %f of %s and %s is not between 0 and 1", distance, a, b)
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the distance of two categories, looked up in both orders
+func getToreCategoryDistance(distances ToreCategoryDistances, a string, b string) float64 {
+	if a == b {
+		return 0.0
+	}
+	if distance, ok := distances[a][b]; ok {
+		return distance
+	}
+	if distance, ok := distances[b][a]; ok {
+		return distance
+	}
+	return 1.0
+}
+
+// Returns the weighted kappas over the tore categories of the coded tokens
+// Distances of categories that are not in toreCategories are ignored
+func getWeightedKappas(
+	agreement Agreement,
+	toreCategories ToreCategories,
+	distances ToreCategoryDistances,
+) WeightedKappas {
+	var knownCategories = map[string]bool{}
+	for _, toreCategory := range toreCategories.Tores {
+		knownCategories[toreCategory] = true
+	}
+	var knownDistances = ToreCategoryDistances{}
+	for a, distancesOfA := range distances {
+		for b, distance := range distancesOfA {
+			if knownCategories[a] && knownCategories[b] {
+				if _, ok := knownDistances[a]; !ok {
+					knownDistances[a] = map[string]float64{}
+				}
+				knownDistances[a][b] = distance
+			}
+		}
+	}
+
+	var categoryRows, reliabilityCategoryRows = getCategoryRows(agreement, func(tore string) string { return tore })
+	var weightedKappas = WeightedKappas{
+		FleissKappa:       calculateWeightedFleissKappa(categoryRows, knownDistances),
+		KrippendorffAlpha: calculateWeightedKrippendorffAlpha(reliabilityCategoryRows, knownDistances),
+	}
+	// Special cases, when Kappas are either smaller than 0 or None because some denominator is 0
+	if (weightedKappas.FleissKappa < 0) || (weightedKappas.FleissKappa != weightedKappas.FleissKappa) {
+		weightedKappas.FleissKappa = 0.0
+	}
+	if (weightedKappas.KrippendorffAlpha < 0) || (weightedKappas.KrippendorffAlpha != weightedKappas.KrippendorffAlpha) {
+		weightedKappas.KrippendorffAlpha = 0.0
+	}
+	return weightedKappas
+}
+
+// Returns for every coded token how often every category was assigned. The category of a code is its tore
+// translated by getCategory
+// In the rows for Fleiss kappa annotations without a code assign categoryOfUncodedAnnotations, as in the data matrix
+// of the unweighted Fleiss kappa. In the rows for Krippendorff's alpha their values are missing and left out
+func getCategoryRows(agreement Agreement, getCategory func(string) string) ([]map[string]int, []map[string]int) {
+	var tokenMap = groupCodeAlternativesByToken(agreement.CodeAlternatives)
+	var categoryRows []map[string]int
+	var reliabilityCategoryRows []map[string]int
+	for _, tokenIndex := range getCodedTokenIndices(agreement) {
+		var codesOfAnnotations = getCodesOfAnnotationsForToken(tokenMap[tokenIndex], agreement.Annotations)
+		var row = map[string]int{}
+		var reliabilityRow = map[string]int{}
+		for _, annotationName := range agreement.Annotations {
+			if len(codesOfAnnotations[annotationName]) == 0 {
+				row[categoryOfUncodedAnnotations]++
+				continue
+			}
+			for _, codeAlternative := range codesOfAnnotations[annotationName] {
+				row[getCategory(codeAlternative.Code.Tore)]++
+				reliabilityRow[getCategory(codeAlternative.Code.Tore)]++
+			}
+		}
+		categoryRows = append(categoryRows, row)
+		reliabilityCategoryRows = append(reliabilityCategoryRows, reliabilityRow)
+	}
+	return categoryRows, reliabilityCategoryRows
+}
+
+// Fleiss kappa with agreement weights of 1 - distance
+func calculateWeightedFleissKappa(categoryRows []map[string]int, distances ToreCategoryDistances) float64 {
+	var categoryTotals = map[string]int{}
+	var sumOfAllCells = 0
+	var sumOfPi = float64(0)
+	var numberOfTokens = 0
+	for _, row := range categoryRows {
+		var codesInRow = 0
+		for _, count := range row {
+			codesInRow += count
+		}
+		if codesInRow < 2 {
+			continue
+		}
+		var weightedAgreements = float64(0)
+		var categories = getSortedCategories(row)
+		for _, c := range categories {
+			var weightedCount = float64(0)
+			for _, k := range categories {
+				weightedCount += (1.0 - getToreCategoryDistance(distances, c, k)) * float64(row[k])
+			}
+			weightedAgreements += float64(row[c]) * (weightedCount - 1.0)
+			categoryTotals[c] += row[c]
+		}
+		sumOfPi += weightedAgreements / float64(codesInRow*(codesInRow-1))
+		sumOfAllCells += codesInRow
+		numberOfTokens++
+	}
+	var pHead = sumOfPi / float64(numberOfTokens)
+
+	var pc = float64(0)
+	var categories = getSortedCategories(categoryTotals)
+	for _, c := range categories {
+		for _, k := range categories {
+			pc += (1.0 - getToreCategoryDistance(distances, c, k)) * float64(categoryTotals[c]) * float64(categoryTotals[k])
+		}
+	}
+	pc /= float64(sumOfAllCells) * float64(sumOfAllCells)
+
+	// This is only for the special case, when denominator is 0
+	if (1.0 - pc) == 0.0 {
+		return 1.0
+	}
+	return (pHead - pc) / (1.0 - pc)
+}
+
+// Krippendorff's alpha with the distances as difference function
+func calculateWeightedKrippendorffAlpha(categoryRows []map[string]int, distances ToreCategoryDistances) float64 {
+	var valueTotals = map[string]int{}
+	var numberOfPairableValues = float64(0)
+	var observedDisagreement = float64(0)
+	for _, row := range categoryRows {
+		var valuesInRow = 0
+		for _, count := range row {
+			valuesInRow += count
+		}
+		// Tokens with a single value cannot be paired and are ignored
+		if valuesInRow < 2 {
+			continue
+		}
+		var categories = getSortedCategories(row)
+		for _, c := range categories {
+			for _, k := range categories {
+				observedDisagreement += float64(row[c]*row[k]) * getToreCategoryDistance(distances, c, k) / float64(valuesInRow-1)
+			}
+			valueTotals[c] += row[c]
+		}
+		numberOfPairableValues += float64(valuesInRow)
+	}
+
+	// Without any pairable values, there is no agreement, as for the other kappas
+	if numberOfPairableValues == 0 {
+		return 0.0
+	}
+	var expectedDisagreement = float64(0)
+	var categories = getSortedCategories(valueTotals)
+	for _, c := range categories {
+		for _, k := range categories {
+			expectedDisagreement += float64(valueTotals[c]*valueTotals[k]) * getToreCategoryDistance(distances, c, k)
+		}
+	}
+	// This is only for the special case, when denominator is 0
+	if expectedDisagreement == 0.0 {
+		return 1.0
+	}
+	return 1.0 - (numberOfPairableValues-1)*observedDisagreement/expectedDisagreement
+}
+
+// Returns the categories of a row in alphabetical order, so floating point sums are always added up in the same order
+func getSortedCategories(row map[string]int) []string {
+	var categories = make([]string, 0, len(row))
+	for category := range row {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	return categories
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+)
+
+// C did not code token 1. For Krippendorff's alpha C's value is missing, so A and B agree on every token
+// For Fleiss kappa C assigns categoryOfUncodedAnnotations and disagrees with A and B
+func TestGetWeightedKappasTreatsUncodedAnnotationsAsMissingInAlpha(t *testing.T) {
+	var agreement = Agreement{
+		Annotations: []string{"A", "B", "C"},
+		Tokens:      makeTestTokens(2),
+		CodeAlternatives: []CodeAlternatives{
+			makeTestCodeAlternative(0, "A", "Pending", "Task", "x", 0),
+			makeTestCodeAlternative(1, "B", "Pending", "Task", "x", 0),
+			makeTestCodeAlternative(2, "C", "Pending", "Task", "x", 0),
+			makeTestCodeAlternative(3, "A", "Pending", "Goal", "y", 1),
+			makeTestCodeAlternative(4, "B", "Pending", "Goal", "y", 1),
+		},
+	}
+	var distances = ToreCategoryDistances{"Task": {"Goal": 0.5}}
+	var weightedKappas = getWeightedKappas(agreement, testToreCategories, distances)
+	testFloatsAreEqual(t, "krippendorffAlpha", weightedKappas.KrippendorffAlpha, 1)
+	if weightedKappas.FleissKappa >= 1 {
+		t.Errorf("fleissKappa = %f, want below 1", weightedKappas.FleissKappa)
+	}
+}
+
+func TestGetToreCategoryDistancesRejectsDistancesOutsideOfZeroAndOne(t *testing.T) {
+	for _, distance := range []float64{-0.5, 1.5} {
+		if _, err := getToreCategoryDistances(ToreCategoryDistances{"Task": {"Goal": distance}}); err == nil {
+			t.Errorf("distance %f was accepted", distance)
+		}
+	}
+	if _, err := getToreCategoryDistances(ToreCategoryDistances{"Task": {"Goal": 0, "Software": 1}}); err != nil {
+		t.Errorf("valid distances were rejected: %s", err)
+	}
+}
+
+// Only A coded tokens 0 to 3 and C coded no token, but uncoded annotations are no level
+// So only the levels of tokens 4 and 5 are compared, and A and B agree on them
+func TestGetAggregatedKappasComparesOnlyLevelsOfCodes(t *testing.T) {
+	var agreement = Agreement{
+		Annotations: []string{"A", "B", "C"},
+		Tokens:      makeTestTokens(6),
+		CodeAlternatives: []CodeAlternatives{
+			makeTestCodeAlternative(0, "A", "Pending", "Task", "x", 0),
+			makeTestCodeAlternative(1, "A", "Pending", "Goal", "x", 1),
+			makeTestCodeAlternative(2, "A", "Pending", "Software", "x", 2),
+			makeTestCodeAlternative(3, "A", "Pending", "Feature", "x", 3),
+			makeTestCodeAlternative(4, "A", "Pending", "Task", "y", 4),
+			makeTestCodeAlternative(5, "B", "Pending", "Goal", "y", 4),
+			makeTestCodeAlternative(6, "A", "Pending", "Software", "z", 5),
+			makeTestCodeAlternative(7, "B", "Pending", "Feature", "z", 5),
+		},
+	}
+	var kappas = getAggregatedKappas(agreement, aggregationLevelToreLevel, defaultToreLevelMapping)
+	testFloatsAreEqual(t, "fleissKappa", kappas[fleissKappaName], 1)
+	testFloatsAreEqual(t, "krippendorffAlpha", kappas[krippendorffAlphaName], 1)
+}